@@ -5,7 +5,10 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"github.com/GolangDeveloperAlmir/order-service/internal/catalog"
 	"github.com/GolangDeveloperAlmir/order-service/internal/config"
+	"github.com/GolangDeveloperAlmir/order-service/internal/fx"
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
 	"github.com/GolangDeveloperAlmir/order-service/internal/order/repository/postgres"
 	"github.com/GolangDeveloperAlmir/order-service/internal/order/service"
 	http "github.com/GolangDeveloperAlmir/order-service/internal/order/transport/http"
@@ -18,6 +21,9 @@ import (
 	"github.com/GolangDeveloperAlmir/order-service/internal/platform/observability"
 	"github.com/GolangDeveloperAlmir/order-service/internal/platform/outbox"
 	"github.com/GolangDeveloperAlmir/order-service/internal/platform/saga"
+	"github.com/GolangDeveloperAlmir/order-service/internal/promotion"
+	"github.com/GolangDeveloperAlmir/order-service/internal/risk"
+	"github.com/GolangDeveloperAlmir/order-service/internal/tax"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	httpstd "net/http"
 	pprof "net/http/pprof"
@@ -52,28 +58,109 @@ func Run(ctx context.Context, cfg *config.Config, logger *log.Logger) error {
 	}
 	defer pool.Close()
 
-	tx := db.NewTxManager(pool)
-	orderRepo := postgres.New(pool)
-	orderSvc := service.New(orderRepo, tx, logger)
+	tx := db.NewTxManager(pool, logger)
+	var repoOpts []postgres.Option
+	switch {
+	case cfg.CursorSecret != "":
+		repoOpts = append(repoOpts, postgres.WithCursorSecret([]byte(cfg.CursorSecret)))
+	case cfg.AppEnv == "prod":
+		return errors.New("CURSOR_SECRET is required in prod")
+	default:
+		logger.Warn("CURSOR_SECRET is not set; pagination cursors will not work across replicas or restarts")
+	}
+	orderRepo, err := postgres.New(pool, logger, repoOpts...)
+	if err != nil {
+		return fmt.Errorf("order repo: %w", err)
+	}
+	var postalCountries []string
+	for _, c := range strings.Split(cfg.PostalCodeCountries, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			postalCountries = append(postalCountries, strings.ToUpper(c))
+		}
+	}
+	postal, err := domain.NewPostalCodeFormats(postalCountries)
+	if err != nil {
+		return fmt.Errorf("postal codes: %w", err)
+	}
+	taxCalc, err := tax.NewCalculator(tax.NewStore(pool, logger), cfg.TaxPricesInclusive, tax.Rounding(cfg.TaxRounding))
+	if err != nil {
+		return fmt.Errorf("tax: %w", err)
+	}
+	reporting := strings.ToUpper(strings.TrimSpace(cfg.ReportingCurrency))
+	if _, ok := domain.LookupCurrency(reporting); reporting != "" && !ok {
+		return fmt.Errorf("reporting currency: unknown currency %q", reporting)
+	}
+	sgStore := saga.NewStore(pool, logger, saga.WithStaleAfter(cfg.SagaStepTimeout))
+	svcOpts := []service.Option{
+		service.WithSagas(sgStore),
+		service.WithPostalCodeFormats(postal),
+		service.WithPromotions(promotion.NewStore(pool, logger)),
+		service.WithTax(taxCalc),
+		service.WithReporting(reporting, fx.NewStore(pool, logger)),
+	}
+	limits := service.Limits{
+		MaxOpenOrders:    cfg.CustomerMaxOpenOrders,
+		MaxOrdersPerHour: cfg.CustomerMaxOrdersPerHour,
+		MaxValuePerDay:   cfg.CustomerMaxDailyValue,
+	}
+	if limits.MaxValuePerDay > 0 && reporting == "" {
+		return errors.New("customer limits: a daily value limit needs a reporting currency")
+	}
+	if limits.Enabled() {
+		svcOpts = append(svcOpts, service.WithLimits(limits))
+	}
+	if cfg.RiskRulesFile != "" {
+		rules, err := risk.LoadConfig(cfg.RiskRulesFile)
+		if err != nil {
+			return fmt.Errorf("risk rules: %w", err)
+		}
+		riskStore := risk.NewStore(pool, logger)
+		engine, err := risk.NewEngine(rules, riskStore)
+		if err != nil {
+			return fmt.Errorf("risk rules: %w", err)
+		}
+		svcOpts = append(svcOpts, service.WithRisk(engine, riskStore))
+	}
+	orderSvc := service.New(orderRepo, catalog.NewPostgres(pool, logger), tx, logger, svcOpts...)
 
 	idem := idempotency.NewStore(pool)
 
-	prod := kafka.NewProducer(cfg.KafkaBrokers, cfg.KafkaTopicOrders)
+	prod := kafka.NewProducer(cfg.KafkaBrokers, cfg.KafkaTopicOrders, logger)
 	defer func() {
 		if err := prod.Close(); err != nil {
 			logger.Error("failed to close kafka producer", log.Err(err))
 		}
 	}()
+	dlq := kafka.NewProducer(cfg.KafkaBrokers, cfg.KafkaTopicDLQ, logger)
+	defer func() {
+		if err := dlq.Close(); err != nil {
+			logger.Error("failed to close kafka dlq producer", log.Err(err))
+		}
+	}()
 
-	relay := outbox.New(pool, prod, cfg.OutboxInterval, cfg.OutboxBatch, logger)
+	relay := outbox.New(pool, prod, cfg.OutboxInterval, cfg.OutboxBatch, logger,
+		outbox.WithDeadLetters(dlq, cfg.OutboxMaxAttempts, cfg.KafkaTopicOrders))
 	go func() {
 		if err := relay.Run(ctx); err != nil {
 			return
 		}
 	}()
 
-	sgStore := saga.NewStore(pool)
+	ttlByCurrency, err := service.ParseTTLByCurrency(cfg.OrderTTLByCurrency)
+	if err != nil {
+		return fmt.Errorf("order ttl: %w", err)
+	}
+	if expiry := (service.ExpiryPolicy{TTL: cfg.OrderTTL, ByCurrency: ttlByCurrency}); expiry.Enabled() {
+		expirer := service.NewExpirer(orderSvc, expiry, cfg.OrderExpiryInterval, cfg.OrderExpiryBatch, logger)
+		go func() {
+			if err := expirer.Run(ctx); err != nil {
+				return
+			}
+		}()
+	}
+
 	sgMgr := saga.NewManager(sgStore, logger)
+	orderSvc.RegisterSagaActions(sgMgr)
 	go func() {
 		if err := sgMgr.RunPoller(ctx); err != nil {
 			return
@@ -106,6 +193,9 @@ func Run(ctx context.Context, cfg *config.Config, logger *log.Logger) error {
 	debugMux.HandleFunc("/debug/pprof/profile", pprof.Profile)
 	debugMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	debugMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	if cfg.OutboxAdminToken != "" {
+		debugMux.Handle("/debug/outbox/", outbox.NewAdmin(pool, logger).Handler(cfg.OutboxAdminToken))
+	}
 
 	debugSrv := &httpstd.Server{
 		Addr:              cfg.DebugAddr,
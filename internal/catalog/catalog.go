@@ -0,0 +1,59 @@
+// Package catalog resolves the unit prices orders are charged at.
+package catalog
+
+import (
+	"context"
+	"sync"
+)
+
+// Price is the unit price of a SKU in one currency, in minor units, and the
+// tax class the SKU is taxed under.
+type Price struct {
+	SKU        string
+	Currency   string
+	PriceMinor int64
+	TaxClass   string
+}
+
+// Memory is an in-memory price list, for tests and local runs.
+type Memory struct {
+	mu     sync.RWMutex
+	prices map[string]map[string]Price // sku -> currency -> price
+}
+
+func NewMemory(prices ...Price) *Memory {
+	m := &Memory{prices: make(map[string]map[string]Price)}
+	for _, p := range prices {
+		m.Set(p)
+	}
+	return m
+}
+
+// Set adds or replaces a price.
+func (m *Memory) Set(p Price) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.prices[p.SKU] == nil {
+		m.prices[p.SKU] = make(map[string]Price)
+	}
+	m.prices[p.SKU][p.Currency] = p
+}
+
+// Prices returns every price of the given SKUs, in all currencies. Unknown
+// SKUs are left out.
+func (m *Memory) Prices(_ context.Context, skus []string) ([]Price, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []Price
+	seen := make(map[string]bool, len(skus))
+	for _, sku := range skus {
+		if seen[sku] {
+			continue
+		}
+		seen[sku] = true
+		for _, p := range m.prices[sku] {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,33 @@
+package catalog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryPrices(t *testing.T) {
+	m := NewMemory(
+		Price{SKU: "A", Currency: "USD", PriceMinor: 100},
+		Price{SKU: "A", Currency: "EUR", PriceMinor: 90},
+		Price{SKU: "B", Currency: "USD", PriceMinor: 50},
+	)
+	m.Set(Price{SKU: "B", Currency: "USD", PriceMinor: 55})
+
+	prices, err := m.Prices(context.Background(), []string{"A", "B", "A", "missing"})
+	if err != nil {
+		t.Fatalf("prices: %v", err)
+	}
+	got := make(map[string]int64)
+	for _, p := range prices {
+		got[p.SKU+"/"+p.Currency] = p.PriceMinor
+	}
+	want := map[string]int64{"A/USD": 100, "A/EUR": 90, "B/USD": 55}
+	if len(got) != len(want) || len(prices) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("%s: got %d want %d", k, got[k], v)
+		}
+	}
+}
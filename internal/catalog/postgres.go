@@ -0,0 +1,48 @@
+package catalog
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Postgres reads prices from the catalog_prices table.
+type Postgres struct {
+	pool *pgxpool.Pool
+	log  *log.Logger
+}
+
+func NewPostgres(pool *pgxpool.Pool, logger *log.Logger) *Postgres {
+	return &Postgres{pool: pool, log: logger}
+}
+
+// Prices returns every active price of the given SKUs, in all currencies.
+// Unknown SKUs are left out.
+func (p *Postgres) Prices(ctx context.Context, skus []string) ([]Price, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT sku, currency, price_minor, tax_class
+		FROM catalog_prices
+		WHERE sku = ANY($1) AND active`, skus)
+	if err != nil {
+		p.log.Error("failed to query catalog prices", log.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prices []Price
+	for rows.Next() {
+		var pr Price
+		if err := rows.Scan(&pr.SKU, &pr.Currency, &pr.PriceMinor, &pr.TaxClass); err != nil {
+			p.log.Error("failed to scan catalog price", log.Err(err))
+			return nil, err
+		}
+		prices = append(prices, pr)
+	}
+	if err := rows.Err(); err != nil {
+		p.log.Error("failed to query catalog prices", log.Err(err))
+		return nil, err
+	}
+
+	return prices, nil
+}
@@ -17,12 +17,54 @@ type Config struct {
 	TLSCertFile  string
 	TLSKeyFile   string
 	DebugAddr    string
+	CursorSecret string
+
+	// PostalCodeCountries lists the countries whose postal code format is
+	// enforced on order addresses, comma separated.
+	PostalCodeCountries string
+
+	// TaxPricesInclusive says catalog prices already contain tax.
+	TaxPricesInclusive bool
+	// TaxRounding is "line" or "order".
+	TaxRounding string
+
+	// ReportingCurrency is the currency order totals are converted to for
+	// reporting; empty disables the conversion.
+	ReportingCurrency string
+
+	// OrderTTL is how long an order may stay unpaid before it is cancelled;
+	// zero disables expiry. OrderTTLByCurrency overrides it per currency,
+	// e.g. "JPY=1h,EUR=45m".
+	OrderTTL            time.Duration
+	OrderTTLByCurrency  string
+	OrderExpiryInterval time.Duration
+	OrderExpiryBatch    int
+
+	// SagaStepTimeout is how long a saga step may run before another poller
+	// picks it again.
+	SagaStepTimeout time.Duration
+
+	// RiskRulesFile is a JSON rule set for fraud scoring on order creation;
+	// empty disables scoring.
+	RiskRulesFile string
+
+	// Per-customer limits on Create; zero disables a limit. The daily value
+	// is in minor units of the reporting currency.
+	CustomerMaxOpenOrders    int
+	CustomerMaxOrdersPerHour int
+	CustomerMaxDailyValue    int64
 
 	KafkaBrokers     string
 	KafkaTopicOrders string
 	KafkaTopicDLQ    string
 	OutboxInterval   time.Duration
 	OutboxBatch      int
+	// OutboxMaxAttempts is how often an event is tried before it goes to
+	// KafkaTopicDLQ; zero retries forever.
+	OutboxMaxAttempts int
+	// OutboxAdminToken enables the outbox admin API on the debug server;
+	// callers send it as a bearer token.
+	OutboxAdminToken string
 
 	RateLimitRPS   float64
 	RateLimitBurst int
@@ -69,6 +111,20 @@ func mustInt(val string, def int) int {
 	return i
 }
 
+func mustBool(val string, def bool) bool {
+	if val == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Panicf("invalid boolean %q: %v", val, err)
+		return def
+	}
+
+	return b
+}
+
 func Load() *Config {
 	return &Config{
 		AppEnv:       getEnv("APP_ENV", "local"),
@@ -80,12 +136,35 @@ func Load() *Config {
 		TLSCertFile:  getEnv("TLS_CERT", ""),
 		TLSKeyFile:   getEnv("TLS_KEY", ""),
 		DebugAddr:    getEnv("DEBUG_ADDR", ":9090"),
+		CursorSecret: getEnv("CURSOR_SECRET", ""),
+
+		PostalCodeCountries: getEnv("POSTAL_CODE_COUNTRIES", "US,CA,GB,DE,FR,NL"),
+
+		TaxPricesInclusive: mustBool(os.Getenv("TAX_PRICES_INCLUSIVE"), false),
+		TaxRounding:        getEnv("TAX_ROUNDING", "line"),
+
+		ReportingCurrency: getEnv("REPORTING_CURRENCY", ""),
+
+		OrderTTL:            mustDur(os.Getenv("ORDER_TTL"), 0),
+		OrderTTLByCurrency:  getEnv("ORDER_TTL_BY_CURRENCY", ""),
+		OrderExpiryInterval: mustDur(getEnv("ORDER_EXPIRY_INTERVAL", "1m"), time.Minute),
+		OrderExpiryBatch:    mustInt(getEnv("ORDER_EXPIRY_BATCH", "100"), 100),
+
+		SagaStepTimeout: mustDur(getEnv("SAGA_STEP_TIMEOUT", "5m"), 5*time.Minute),
+
+		RiskRulesFile: getEnv("RISK_RULES_FILE", ""),
+
+		CustomerMaxOpenOrders:    mustInt(os.Getenv("CUSTOMER_MAX_OPEN_ORDERS"), 0),
+		CustomerMaxOrdersPerHour: mustInt(os.Getenv("CUSTOMER_MAX_ORDERS_PER_HOUR"), 0),
+		CustomerMaxDailyValue:    int64(mustInt(os.Getenv("CUSTOMER_MAX_DAILY_VALUE"), 0)),
 
-		KafkaBrokers:     getEnv("KAFKA_BROKERS", "localhost:19092"),
-		KafkaTopicOrders: getEnv("KAFKA_TOPIC_ORDERS", "orders"),
-		KafkaTopicDLQ:    getEnv("KAFKA_TOPIC_DLQ", "orders.dlq"),
-		OutboxInterval:   mustDur(getEnv("OUTBOX_RELAY_INTERVAL", "2s"), 2*time.Second),
-		OutboxBatch:      mustInt(getEnv("OUTBOX_RELAY_BATCH", "200"), 200),
+		KafkaBrokers:      getEnv("KAFKA_BROKERS", "localhost:19092"),
+		KafkaTopicOrders:  getEnv("KAFKA_TOPIC_ORDERS", "orders"),
+		KafkaTopicDLQ:     getEnv("KAFKA_TOPIC_DLQ", "orders.dlq"),
+		OutboxInterval:    mustDur(getEnv("OUTBOX_RELAY_INTERVAL", "15s"), 15*time.Second),
+		OutboxBatch:       mustInt(getEnv("OUTBOX_RELAY_BATCH", "200"), 200),
+		OutboxMaxAttempts: mustInt(getEnv("OUTBOX_MAX_ATTEMPTS", "10"), 10),
+		OutboxAdminToken:  getEnv("OUTBOX_ADMIN_TOKEN", ""),
 
 		RateLimitRPS:   float64(mustInt(getEnv("RATE_LIMIT_RPS", "10"), 10)),
 		RateLimitBurst: mustInt(getEnv("RATE_LIMIT_BURST", "20"), 20),
@@ -0,0 +1,90 @@
+// Package fx converts amounts between currencies using timestamped rates.
+package fx
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+)
+
+// ErrNoRate is returned when no rate is known for a currency pair.
+var ErrNoRate = errors.New("no exchange rate")
+
+// Rate converts one unit of Base into Value units of Quote. AsOf is when the
+// rate became effective.
+type Rate struct {
+	Base  string
+	Quote string
+	Value *big.Rat
+	AsOf  time.Time
+}
+
+// Source looks up the rate from base to quote in effect at a point in time.
+type Source interface {
+	Rate(ctx context.Context, base, quote string, at time.Time) (Rate, error)
+}
+
+// Convert turns amount minor units of a currency with exponent fromExp into
+// minor units of a currency with exponent toExp at rate. Halves are rounded
+// away from zero. A result that does not fit in int64 is
+// domain.ErrAmountOverflow.
+func Convert(amount int64, fromExp, toExp int, rate *big.Rat) (int64, error) {
+	x := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), rate)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(toExp-fromExp))), nil))
+	if toExp >= fromExp {
+		x.Mul(x, scale)
+	} else {
+		x.Quo(x, scale)
+	}
+
+	neg := x.Sign() < 0
+	x.Abs(x)
+	q, m := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	if m.Lsh(m, 1).Cmp(x.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if neg {
+		q.Neg(q)
+	}
+	if !q.IsInt64() {
+		return 0, domain.ErrAmountOverflow
+	}
+	return q.Int64(), nil
+}
+
+// Static is a fixed set of rates, for tests and local runs.
+type Static []Rate
+
+// Rate returns the latest rate from base to quote effective at at, using the
+// inverse of a quote to base rate when needed.
+func (s Static) Rate(_ context.Context, base, quote string, at time.Time) (Rate, error) {
+	var (
+		best  Rate
+		found bool
+	)
+	for _, r := range s {
+		if r.AsOf.After(at) || (found && !r.AsOf.After(best.AsOf)) {
+			continue
+		}
+		switch {
+		case r.Base == base && r.Quote == quote:
+			best, found = r, true
+		case r.Base == quote && r.Quote == base:
+			best, found = Rate{Base: base, Quote: quote, Value: new(big.Rat).Inv(r.Value), AsOf: r.AsOf}, true
+		}
+	}
+	if !found {
+		return Rate{}, ErrNoRate
+	}
+	return best, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
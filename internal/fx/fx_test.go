@@ -0,0 +1,72 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+)
+
+func TestConvertAcrossExponents(t *testing.T) {
+	cases := []struct {
+		amount         int64
+		fromExp, toExp int
+		rate           string
+		want           int64
+	}{
+		{1000, 2, 2, "0.9", 900},       // 10.00 USD -> 9.00 EUR
+		{1000, 2, 0, "150.25", 1503},   // 10.00 USD -> 1502.5 JPY, rounded half up
+		{1500, 0, 2, "0.0066", 990},    // 1500 JPY -> 9.90 USD
+		{1000, 2, 3, "0.307", 3070},    // 10.00 USD -> 3.070 KWD
+		{-1000, 2, 0, "150.25", -1503}, // halves round away from zero
+		{333, 2, 2, "1", 333},
+	}
+	for _, c := range cases {
+		r, _ := new(big.Rat).SetString(c.rate)
+		if got, err := Convert(c.amount, c.fromExp, c.toExp, r); err != nil || got != c.want {
+			t.Fatalf("Convert(%d, %d, %d, %s) = %d, %v, want %d", c.amount, c.fromExp, c.toExp, c.rate, got, err, c.want)
+		}
+	}
+}
+
+func TestConvertOverflow(t *testing.T) {
+	for _, c := range []struct {
+		amount         int64
+		fromExp, toExp int
+		rate           string
+	}{
+		{math.MaxInt64 / 2, 2, 2, "3"},   // rate above 1
+		{math.MaxInt64 / 2, 0, 3, "1"},   // higher exponent
+		{math.MinInt64 / 2, 2, 2, "2.5"}, // negative
+	} {
+		r, _ := new(big.Rat).SetString(c.rate)
+		if got, err := Convert(c.amount, c.fromExp, c.toExp, r); !errors.Is(err, domain.ErrAmountOverflow) {
+			t.Errorf("Convert(%d, %d, %d, %s) = %d, %v, want ErrAmountOverflow", c.amount, c.fromExp, c.toExp, c.rate, got, err)
+		}
+	}
+}
+
+func TestStaticPicksLatestRateAndInverts(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	s := Static{
+		{Base: "EUR", Quote: "USD", Value: big.NewRat(11, 10), AsOf: day},
+		{Base: "EUR", Quote: "USD", Value: big.NewRat(12, 10), AsOf: day.Add(24 * time.Hour)},
+	}
+	ctx := context.Background()
+
+	r, err := s.Rate(ctx, "EUR", "USD", day.Add(time.Hour))
+	if err != nil || r.Value.Cmp(big.NewRat(11, 10)) != 0 {
+		t.Fatalf("rate before update: %+v %v", r, err)
+	}
+	r, err = s.Rate(ctx, "USD", "EUR", day.Add(48*time.Hour))
+	if err != nil || r.Value.Cmp(big.NewRat(10, 12)) != 0 || r.Base != "USD" {
+		t.Fatalf("inverse rate: %+v %v", r, err)
+	}
+	if _, err := s.Rate(ctx, "EUR", "USD", day.Add(-time.Hour)); !errors.Is(err, ErrNoRate) {
+		t.Fatalf("rate before any snapshot: want ErrNoRate, got %v", err)
+	}
+}
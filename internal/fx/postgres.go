@@ -0,0 +1,54 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store reads rates from the fx_rates table.
+type Store struct {
+	pool *pgxpool.Pool
+	log  *log.Logger
+}
+
+func NewStore(pool *pgxpool.Pool, logger *log.Logger) *Store {
+	return &Store{pool: pool, log: logger}
+}
+
+// Rate returns the latest rate from base to quote effective at at. When only
+// the opposite pair is stored its inverse is used.
+func (s *Store) Rate(ctx context.Context, base, quote string, at time.Time) (Rate, error) {
+	var (
+		rb, rq, value string
+		asOf          time.Time
+	)
+	err := s.pool.QueryRow(ctx, `
+		SELECT base, quote, rate::text, as_of
+		FROM fx_rates
+		WHERE ((base=$1 AND quote=$2) OR (base=$2 AND quote=$1)) AND as_of <= $3
+		ORDER BY as_of DESC, base=$1 DESC
+		LIMIT 1`, base, quote, at).Scan(&rb, &rq, &value, &asOf)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Rate{}, ErrNoRate
+		}
+		s.log.Error("failed to query fx rate", log.Err(err))
+		return Rate{}, err
+	}
+	v, ok := new(big.Rat).SetString(value)
+	if !ok || v.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("invalid fx rate %q for %s/%s", value, rb, rq)
+	}
+	if rb != base {
+		v.Inv(v)
+	}
+
+	return Rate{Base: base, Quote: quote, Value: v, AsOf: asOf}, nil
+}
@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Address is a postal address. Country is an ISO 3166-1 alpha-2 code.
+type Address struct {
+	Name       string `json:"name"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Country    string `json:"country"`
+}
+
+// Validate checks the fields every address needs. Postal code formats are
+// checked separately by PostalCodeFormats, as they are configured per
+// deployment.
+func (a *Address) Validate() error {
+	if strings.TrimSpace(a.Name) == "" || strings.TrimSpace(a.Line1) == "" || strings.TrimSpace(a.City) == "" {
+		return &ValidationError{Msg: "address: name, line1 and city are required"}
+	}
+	if !isoCountries[a.Country] {
+		return &ValidationError{Msg: fmt.Sprintf("address: unknown country %q", a.Country)}
+	}
+	return nil
+}
+
+// ChangeAddresses replaces the addresses of an order that has not been paid
+// yet. A nil address leaves the current one unchanged.
+func (o *Order) ChangeAddresses(shipping, billing *Address) error {
+	if !o.modifiable() {
+		return ErrNotModifiable
+	}
+	if err := validateAddresses(shipping, billing); err != nil {
+		return err
+	}
+	if shipping != nil {
+		o.ShippingAddress = shipping
+	}
+	if billing != nil {
+		o.BillingAddress = billing
+	}
+	o.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func validateAddresses(addrs ...*Address) error {
+	for _, a := range addrs {
+		if a == nil {
+			continue
+		}
+		if err := a.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PostalCodeFormats holds the postal code patterns enforced per country.
+// Countries without an entry accept any postal code.
+type PostalCodeFormats map[string]*regexp.Regexp
+
+// NewPostalCodeFormats selects the built-in formats of the given countries.
+func NewPostalCodeFormats(countries []string) (PostalCodeFormats, error) {
+	f := make(PostalCodeFormats, len(countries))
+	for _, c := range countries {
+		re, ok := postalCodePatterns[c]
+		if !ok {
+			return nil, fmt.Errorf("no postal code format for country %q", c)
+		}
+		f[c] = re
+	}
+	return f, nil
+}
+
+// Check validates the postal code of a, if a is set and its country has a
+// format.
+func (f PostalCodeFormats) Check(a *Address) error {
+	if a == nil {
+		return nil
+	}
+	re, ok := f[a.Country]
+	if !ok {
+		return nil
+	}
+	if !re.MatchString(strings.TrimSpace(a.PostalCode)) {
+		return &ValidationError{Msg: fmt.Sprintf("address: invalid postal code %q for %s", a.PostalCode, a.Country)}
+	}
+	return nil
+}
+
+var postalCodePatterns = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[A-Za-z]\d[A-Za-z] ?\d[A-Za-z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Za-z]{1,2}\d[A-Za-z\d]? ?\d[A-Za-z]{2}$`),
+	"IE": regexp.MustCompile(`^[A-Za-z]\d[\dWw] ?[A-Za-z\d]{4}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"KZ": regexp.MustCompile(`^\d{6}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Za-z]{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"RU": regexp.MustCompile(`^\d{6}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// isoCountries is the set of officially assigned ISO 3166-1 alpha-2 codes.
+var isoCountries = func() map[string]bool {
+	const codes = `AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ ` +
+		`CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO FR ` +
+		`GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU ID IE IL IM IN IO IQ IR IS IT JE JM JO JP ` +
+		`KE KG KH KI KM KN KP KR KW KY KZ LA LB LC LI LK LR LS LT LU LV LY MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW MX MY MZ ` +
+		`NA NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF PG PH PK PL PM PN PR PS PT PW PY QA RE RO RS RU RW ` +
+		`SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ ` +
+		`UA UG UM US UY UZ VA VC VE VG VI VN VU WF WS YE YT ZA ZM ZW`
+	m := make(map[string]bool)
+	for _, c := range strings.Fields(codes) {
+		m[c] = true
+	}
+	return m
+}()
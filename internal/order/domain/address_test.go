@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func addr(country, postal string) *Address {
+	return &Address{Name: "Jane Doe", Line1: "1 Main St", City: "Springfield", PostalCode: postal, Country: country}
+}
+
+func TestNewValidatesAddresses(t *testing.T) {
+	items := []Item{{SKU: "A", Quantity: 1, Price: usd(100)}}
+
+	o, err := New(uuid.New(), "USD", items, addr("US", "12345"), nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if o.ShippingAddress == nil || o.BillingAddress != nil {
+		t.Fatalf("addresses not kept: %+v %+v", o.ShippingAddress, o.BillingAddress)
+	}
+
+	var ve *ValidationError
+	if _, err := New(uuid.New(), "USD", items, addr("XX", "12345"), nil); !errors.As(err, &ve) {
+		t.Fatalf("unknown country: want ValidationError, got %v", err)
+	}
+	if _, err := New(uuid.New(), "USD", items, nil, &Address{Country: "US"}); !errors.As(err, &ve) {
+		t.Fatalf("incomplete address: want ValidationError, got %v", err)
+	}
+}
+
+func TestPostalCodeFormats(t *testing.T) {
+	if _, err := NewPostalCodeFormats([]string{"US", "ZZ"}); err == nil {
+		t.Fatalf("unknown country should be rejected")
+	}
+	f, err := NewPostalCodeFormats([]string{"US", "GB", "CA"})
+	if err != nil {
+		t.Fatalf("formats: %v", err)
+	}
+
+	for _, tc := range []struct {
+		a    *Address
+		want bool
+	}{
+		{addr("US", "12345"), true},
+		{addr("US", "12345-6789"), true},
+		{addr("US", "1234"), false},
+		{addr("GB", "SW1A 1AA"), true},
+		{addr("GB", "12345"), false},
+		{addr("CA", "K1A 0B1"), true},
+		{addr("DE", "anything"), true}, // not configured
+		{nil, true},
+	} {
+		if err := f.Check(tc.a); (err == nil) != tc.want {
+			t.Errorf("%+v: got %v, want ok=%v", tc.a, err, tc.want)
+		}
+	}
+}
+
+func TestChangeAddressesOnlyWhileCreated(t *testing.T) {
+	o, err := New(uuid.New(), "USD", []Item{{SKU: "A", Quantity: 1, Price: usd(100)}}, addr("US", "12345"), nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := o.ChangeAddresses(nil, addr("DE", "10115")); err != nil {
+		t.Fatalf("change: %v", err)
+	}
+	if o.ShippingAddress.Country != "US" || o.BillingAddress.Country != "DE" {
+		t.Fatalf("after change: %+v %+v", o.ShippingAddress, o.BillingAddress)
+	}
+
+	if err := o.MarkPaid(); err != nil {
+		t.Fatalf("pay: %v", err)
+	}
+	if err := o.ChangeAddresses(addr("FR", "75001"), nil); !errors.Is(err, ErrNotModifiable) {
+		t.Fatalf("change after payment: want ErrNotModifiable, got %v", err)
+	}
+}
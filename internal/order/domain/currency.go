@@ -0,0 +1,40 @@
+package domain
+
+import "strings"
+
+// Currency is an ISO 4217 currency and the number of digits of its minor
+// unit: 2 for USD (cents), 0 for JPY, 3 for KWD.
+type Currency struct {
+	Code     string
+	Exponent int
+}
+
+// LookupCurrency returns the registered currency with the given code.
+func LookupCurrency(code string) (Currency, bool) {
+	exp, ok := currencies[code]
+	if !ok {
+		return Currency{}, false
+	}
+	return Currency{Code: code, Exponent: exp}, true
+}
+
+// currencies maps the active ISO 4217 codes to their minor unit exponent.
+var currencies = func() map[string]int {
+	const (
+		exp2 = `AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BMD BND BOB BOV BRL BSD BTN BWP BYN BZD ` +
+			`CAD CDF CHE CHF CHW CNY COP COU CRC CUP CVE CZK DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GTQ GYD ` +
+			`HKD HNL HTG HUF IDR ILS INR IRR JMD KES KGS KHR KPW KYD KZT LAK LBP LKR LRD LSL MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR MZN ` +
+			`NAD NGN NIO NOK NPR NZD PAB PEN PGK PHP PKR PLN QAR RON RSD RUB SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL ` +
+			`THB TJS TMT TOP TRY TTD TWD TZS UAH USD USN UYU UZS VED VES WST XCD XCG YER ZAR ZMW ZWG`
+		exp0 = `BIF CLP DJF GNF ISK JPY KMF KRW PYG RWF UGX UYI VND VUV XAF XOF XPF`
+		exp3 = `BHD IQD JOD KWD LYD OMR TND`
+		exp4 = `CLF UYW`
+	)
+	m := make(map[string]int)
+	for exp, codes := range []string{exp0, "", exp2, exp3, exp4} {
+		for _, c := range strings.Fields(codes) {
+			m[c] = exp
+		}
+	}
+	return m
+}()
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestLookupCurrencyExponents(t *testing.T) {
+	for code, want := range map[string]int{"USD": 2, "EUR": 2, "JPY": 0, "KRW": 0, "KWD": 3, "BHD": 3, "CLF": 4} {
+		c, ok := LookupCurrency(code)
+		if !ok || c.Exponent != want {
+			t.Fatalf("%s: got %+v ok=%v, want exponent %d", code, c, ok, want)
+		}
+	}
+	if _, ok := LookupCurrency("XYZ"); ok {
+		t.Fatal("XYZ must not be a currency")
+	}
+}
+
+func TestNewRejectsUnknownCurrency(t *testing.T) {
+	_, err := New(uuid.New(), "ABC", []Item{{SKU: "A", Quantity: 1, Price: usd(100)}}, nil, nil)
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("want ValidationError, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Discount is a promotion applied to an order when it was created.
+type Discount struct {
+	Code         string `json:"code"`
+	Kind         string `json:"kind"`
+	Amount       Money  `json:"amount"`
+	FreeShipping bool   `json:"free_shipping,omitempty"`
+}
+
+// ItemsTotal is the sum of all line totals, before discounts.
+func (o *Order) ItemsTotal() Money {
+	total := NewMoney(0, o.Currency)
+	for _, it := range o.Items {
+		// line totals were checked when the order was created
+		total.Amount += it.LineTotal.Amount
+	}
+	return total
+}
+
+// ApplyDiscounts records the discounts of a new order and lowers its total
+// accordingly.
+func (o *Order) ApplyDiscounts(ds []Discount) error {
+	if !o.modifiable() {
+		return ErrNotModifiable
+	}
+	amount := NewMoney(0, o.Currency)
+	for _, d := range ds {
+		if d.Amount.IsNegative() {
+			return &ValidationError{Msg: fmt.Sprintf("discount %s: negative amount", d.Code)}
+		}
+		var err error
+		if amount, err = amount.Add(d.Amount); err != nil {
+			return err
+		}
+	}
+	items := o.ItemsTotal()
+	if amount.Amount > items.Amount {
+		return &ValidationError{Msg: "discounts exceed the order total"}
+	}
+
+	o.Discounts = ds
+	o.DiscountAmount = amount
+	o.SubtotalAmount = NewMoney(items.Amount-amount.Amount, o.Currency)
+	o.TotalAmount = o.SubtotalAmount
+	o.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// FreeShipping reports whether a discount waives shipping.
+func (o *Order) FreeShipping() bool {
+	for _, d := range o.Discounts {
+		if d.FreeShipping {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestApplyDiscounts(t *testing.T) {
+	o, err := New(uuid.New(), "USD", []Item{{SKU: "A", Quantity: 2, Price: usd(500)}}, nil, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	var ve *ValidationError
+	if err := o.ApplyDiscounts([]Discount{{Code: "BIG", Amount: usd(1001)}}); !errors.As(err, &ve) {
+		t.Fatalf("discount above total: want ValidationError, got %v", err)
+	}
+	if err := o.ApplyDiscounts([]Discount{{Code: "P10", Amount: usd(100)}, {Code: "SHIP", Amount: usd(0), FreeShipping: true}}); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if o.TotalAmount.Amount != 900 || o.DiscountAmount.Amount != 100 || o.ItemsTotal().Amount != 1000 || !o.FreeShipping() {
+		t.Fatalf("after discounts: total=%d discount=%d items=%d", o.TotalAmount.Amount, o.DiscountAmount.Amount, o.ItemsTotal().Amount)
+	}
+
+	if err := o.MarkPaid(); err != nil {
+		t.Fatalf("pay: %v", err)
+	}
+	if err := o.MarkShipped(); err != nil {
+		t.Fatalf("ship: %v", err)
+	}
+	r, err := o.RequestReturn(nil, []ReturnLine{{LineID: o.Items[0].LineID, Quantity: 1}}, "")
+	if err != nil {
+		t.Fatalf("return: %v", err)
+	}
+	if got, err := o.ReturnAmount(r); err != nil || got != usd(450) {
+		t.Fatalf("return amount should carry the discount share: got %s want 450 (%v)", got, err)
+	}
+}
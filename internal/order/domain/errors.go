@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is returned when the requested order does not exist.
+	ErrNotFound = errors.New("order not found")
+	// ErrVersionConflict is returned when a write was based on a stale order version.
+	ErrVersionConflict = errors.New("order version conflict")
+	// ErrNotModifiable is returned when an order is changed after it left the
+	// created status.
+	ErrNotModifiable = errors.New("order can only be modified while created")
+	// ErrReturnNotFound is returned when the order has no such return.
+	ErrReturnNotFound = errors.New("return not found")
+	// ErrHoldNotFound is returned when the order has no such active hold.
+	ErrHoldNotFound = errors.New("hold not found")
+
+	// ErrCurrencyMismatch is returned when amounts in different currencies
+	// are combined.
+	ErrCurrencyMismatch = &ValidationError{Msg: "currency mismatch"}
+	// ErrAmountOverflow is returned when an amount does not fit in 64 bits of
+	// minor units.
+	ErrAmountOverflow = &ValidationError{Msg: "amount out of range"}
+	// ErrRiskRejected is returned when a new order fails the fraud checks.
+	ErrRiskRejected = &ValidationError{Msg: "order rejected by risk checks"}
+)
+
+// TransitionError reports a status change rejected by the order state machine.
+type TransitionError struct {
+	From   Status
+	To     Status
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("invalid transition %s -> %s: %s", e.From, e.To, e.Reason)
+}
+
+// ValidationError reports input the domain rejects, e.g. an unknown line.
+type ValidationError struct {
+	Msg string
+}
+
+func (e *ValidationError) Error() string { return e.Msg }
+
+// ReturnTransitionError reports a return status change rejected by the return
+// state machine.
+type ReturnTransitionError struct {
+	From ReturnStatus
+	To   ReturnStatus
+}
+
+func (e *ReturnTransitionError) Error() string {
+	return fmt.Sprintf("invalid return transition %s -> %s", e.From, e.To)
+}
@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatusChange is one entry of an order's status timeline.
+type StatusChange struct {
+	ID        int64     `json:"id"`
+	OrderID   uuid.UUID `json:"order_id"`
+	From      Status    `json:"from,omitempty"`
+	To        Status    `json:"to"`
+	Actor     string    `json:"actor,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HoldReason says why an order was put on hold for manual review.
+type HoldReason string
+
+const (
+	HoldFraud               HoldReason = "fraud"
+	HoldAddressVerification HoldReason = "address_verification"
+	HoldStock               HoldReason = "stock"
+)
+
+func (r HoldReason) valid() bool {
+	switch r {
+	case HoldFraud, HoldAddressVerification, HoldStock:
+		return true
+	}
+	return false
+}
+
+// Hold freezes an order until it is released. An order can carry one active
+// hold per reason.
+type Hold struct {
+	ID         uuid.UUID  `json:"id"`
+	OrderID    uuid.UUID  `json:"order_id"`
+	Reason     HoldReason `json:"reason"`
+	Note       string     `json:"note,omitempty"`
+	PlacedBy   string     `json:"placed_by,omitempty"`
+	PlacedAt   time.Time  `json:"placed_at"`
+	ReleasedBy string     `json:"released_by,omitempty"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
+// OnHold reports whether the order has an active hold.
+func (o *Order) OnHold() bool {
+	return o.Status == StatusOnHold
+}
+
+// PlaceHold puts the order on hold. Only orders still waiting for payment or
+// shipping can be held; the status they had is restored once the last hold
+// is released.
+func (o *Order) PlaceHold(reason HoldReason, note, actor string) (*Hold, error) {
+	if !reason.valid() {
+		return nil, &ValidationError{Msg: fmt.Sprintf("unknown hold reason %q", reason)}
+	}
+	if !o.OnHold() && o.Status != StatusCreated && !o.shippable() {
+		return nil, &TransitionError{From: o.Status, To: StatusOnHold, Reason: "only orders awaiting payment or shipping can be held"}
+	}
+	for _, h := range o.Holds {
+		if h.Reason == reason {
+			return nil, &ValidationError{Msg: fmt.Sprintf("order already has an active %s hold", reason)}
+		}
+	}
+
+	now := time.Now().UTC()
+	h := Hold{ID: uuid.New(), OrderID: o.ID, Reason: reason, Note: note, PlacedBy: actor, PlacedAt: now}
+	o.Holds = append(o.Holds, h)
+	if !o.OnHold() {
+		o.HeldStatus, o.Status = o.Status, StatusOnHold
+	}
+	o.UpdatedAt = now
+
+	return &h, nil
+}
+
+// ReleaseHold releases an active hold. The order goes back to the status it
+// had before it was held when no other hold is left.
+func (o *Order) ReleaseHold(id uuid.UUID, actor string) (*Hold, error) {
+	i := -1
+	for j, h := range o.Holds {
+		if h.ID == id {
+			i = j
+		}
+	}
+	if i < 0 {
+		return nil, ErrHoldNotFound
+	}
+
+	now := time.Now().UTC()
+	h := o.Holds[i]
+	h.ReleasedBy, h.ReleasedAt = actor, &now
+	o.Holds = append(o.Holds[:i:i], o.Holds[i+1:]...)
+	if len(o.Holds) == 0 && o.OnHold() {
+		o.Status, o.HeldStatus = o.HeldStatus, ""
+	}
+	o.UpdatedAt = now
+
+	return &h, nil
+}
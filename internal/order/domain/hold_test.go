@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestHoldBlocksPaymentAndShippingUntilReleased(t *testing.T) {
+	o, err := New(uuid.New(), "USD", []Item{{SKU: "A", Quantity: 1, Price: usd(100)}}, nil, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	fraud, err := o.PlaceHold(HoldFraud, "velocity", "reviewer")
+	if err != nil {
+		t.Fatalf("hold: %v", err)
+	}
+	stock, err := o.PlaceHold(HoldStock, "", "reviewer")
+	if err != nil {
+		t.Fatalf("second hold: %v", err)
+	}
+	if o.Status != StatusOnHold || o.HeldStatus != StatusCreated || len(o.Holds) != 2 {
+		t.Fatalf("after holds: status=%s held=%s holds=%d", o.Status, o.HeldStatus, len(o.Holds))
+	}
+	var ve *ValidationError
+	if _, err := o.PlaceHold(HoldFraud, "", ""); !errors.As(err, &ve) {
+		t.Fatalf("duplicate reason: want ValidationError, got %v", err)
+	}
+	if _, err := o.PlaceHold("whim", "", ""); !errors.As(err, &ve) {
+		t.Fatalf("unknown reason: want ValidationError, got %v", err)
+	}
+
+	var te *TransitionError
+	if err := o.MarkPaid(); !errors.As(err, &te) {
+		t.Fatalf("pay while held: want TransitionError, got %v", err)
+	}
+
+	if _, err := o.ReleaseHold(fraud.ID, "reviewer"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if o.Status != StatusOnHold {
+		t.Fatalf("order released while a hold is left: %s", o.Status)
+	}
+	if _, err := o.ReleaseHold(fraud.ID, "reviewer"); !errors.Is(err, ErrHoldNotFound) {
+		t.Fatalf("release twice: want ErrHoldNotFound, got %v", err)
+	}
+	released, err := o.ReleaseHold(stock.ID, "reviewer")
+	if err != nil || released.ReleasedAt == nil || released.ReleasedBy != "reviewer" {
+		t.Fatalf("release last: %+v %v", released, err)
+	}
+	if o.Status != StatusCreated || o.HeldStatus != "" {
+		t.Fatalf("after release: status=%s held=%s", o.Status, o.HeldStatus)
+	}
+	if err := o.MarkPaid(); err != nil {
+		t.Fatalf("pay after release: %v", err)
+	}
+
+	if _, err := o.PlaceHold(HoldAddressVerification, "", ""); err != nil {
+		t.Fatalf("hold paid order: %v", err)
+	}
+	if err := o.MarkShipped(); !errors.As(err, &te) {
+		t.Fatalf("ship while held: want TransitionError, got %v", err)
+	}
+	if _, err := o.Ship([]ShipmentLine{{LineID: o.Items[0].LineID, Quantity: 1}}, "ups", "1Z"); !errors.As(err, &te) {
+		t.Fatalf("partial ship while held: want TransitionError, got %v", err)
+	}
+	if err := o.Cancel(); err != nil || o.Status != StatusCancelled || o.HeldStatus != "" || len(o.Holds) != 0 {
+		t.Fatalf("cancel held order: status=%s err=%v", o.Status, err)
+	}
+	if _, err := o.PlaceHold(HoldFraud, "", ""); !errors.As(err, &te) {
+		t.Fatalf("hold cancelled order: want TransitionError, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LineChange is one requested change to the lines of an order. With a LineID
+// it sets the quantity of that line, zero removes it; without one it adds a
+// line of SKU at Price.
+type LineChange struct {
+	LineID   uuid.UUID
+	SKU      string
+	Quantity int
+	Price    Money
+	TaxClass string
+}
+
+// QuantityChange is a line whose quantity changed.
+type QuantityChange struct {
+	LineID uuid.UUID `json:"line_id"`
+	SKU    string    `json:"sku"`
+	From   int       `json:"from"`
+	To     int       `json:"to"`
+}
+
+// ItemsDiff describes what ChangeItems did.
+type ItemsDiff struct {
+	Added   []Item           `json:"added"`
+	Removed []Item           `json:"removed"`
+	Changed []QuantityChange `json:"changed"`
+}
+
+// Empty reports whether nothing changed.
+func (d *ItemsDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// ChangeItems applies changes to the lines of an order that has not been
+// paid. With replace, lines that are not named are removed, so changes is the
+// complete new line set. Kept lines keep their price and position; new lines
+// are appended. The totals are reset to the new lines without discounts or
+// tax, which the caller has to apply again.
+func (o *Order) ChangeItems(changes []LineChange, replace bool) (*ItemsDiff, error) {
+	if !o.modifiable() {
+		return nil, ErrNotModifiable
+	}
+	existing := make(map[uuid.UUID]bool, len(o.Items))
+	for _, it := range o.Items {
+		existing[it.LineID] = true
+	}
+	byID := make(map[uuid.UUID]LineChange, len(changes))
+	var added []LineChange
+	for _, c := range changes {
+		if c.LineID == uuid.Nil {
+			if c.SKU == "" || c.Quantity <= 0 {
+				return nil, &ValidationError{Msg: "new lines need a sku and a positive quantity"}
+			}
+			if c.Price.Currency != o.Currency || c.Price.IsNegative() {
+				return nil, &ValidationError{Msg: fmt.Sprintf("invalid price for sku %q", c.SKU)}
+			}
+			added = append(added, c)
+			continue
+		}
+		if !existing[c.LineID] {
+			return nil, &ValidationError{Msg: fmt.Sprintf("unknown line %s", c.LineID)}
+		}
+		if _, dup := byID[c.LineID]; dup {
+			return nil, &ValidationError{Msg: fmt.Sprintf("line %s changed twice", c.LineID)}
+		}
+		if c.Quantity < 0 || (replace && c.Quantity == 0) {
+			return nil, &ValidationError{Msg: fmt.Sprintf("line %s: invalid quantity %d", c.LineID, c.Quantity)}
+		}
+		byID[c.LineID] = c
+	}
+
+	diff := &ItemsDiff{Added: []Item{}, Removed: []Item{}, Changed: []QuantityChange{}}
+	lines := make([]Item, 0, len(o.Items)+len(added))
+	for _, it := range o.Items {
+		c, named := byID[it.LineID]
+		switch {
+		case !named && replace, named && c.Quantity == 0:
+			diff.Removed = append(diff.Removed, it)
+			continue
+		case !named:
+		case c.SKU != "" && c.SKU != it.SKU:
+			return nil, &ValidationError{Msg: fmt.Sprintf("line %s is sku %q, not %q", it.LineID, it.SKU, c.SKU)}
+		case c.Quantity != it.Quantity:
+			diff.Changed = append(diff.Changed, QuantityChange{LineID: it.LineID, SKU: it.SKU, From: it.Quantity, To: c.Quantity})
+			it.Quantity = c.Quantity
+		}
+		lines = append(lines, it)
+	}
+	for _, c := range added {
+		it := Item{LineID: uuid.New(), SKU: c.SKU, Quantity: c.Quantity, Price: c.Price, TaxClass: c.TaxClass}
+		lines = append(lines, it)
+	}
+	if len(lines) == 0 {
+		return nil, &ValidationError{Msg: "an order needs at least one line"}
+	}
+
+	total := NewMoney(0, o.Currency)
+	for i := range lines {
+		var err error
+		if lines[i].LineTotal, err = lines[i].Price.Mul(int64(lines[i].Quantity)); err != nil {
+			return nil, err
+		}
+		if total, err = total.Add(lines[i].LineTotal); err != nil {
+			return nil, err
+		}
+		lines[i].Tax = NewMoney(0, o.Currency)
+	}
+	for _, it := range lines[len(lines)-len(added):] {
+		diff.Added = append(diff.Added, it)
+	}
+
+	zero := NewMoney(0, o.Currency)
+	o.Items = lines
+	o.Discounts, o.DiscountAmount = nil, zero
+	o.TaxAmount = zero
+	o.SubtotalAmount, o.TotalAmount = total, total
+	o.UpdatedAt = time.Now().UTC()
+	return diff, nil
+}
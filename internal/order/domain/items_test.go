@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestChangeItems(t *testing.T) {
+	newOrder := func() *Order {
+		o, err := New(uuid.New(), "USD", []Item{
+			{SKU: "A", Quantity: 2, Price: usd(100)},
+			{SKU: "B", Quantity: 1, Price: usd(50)},
+		}, nil, nil)
+		if err != nil {
+			t.Fatalf("new: %v", err)
+		}
+		if err := o.ApplyDiscounts([]Discount{{Code: "X", Amount: usd(10)}}); err != nil {
+			t.Fatalf("discount: %v", err)
+		}
+		return o
+	}
+
+	o := newOrder()
+	a, b := o.Items[0], o.Items[1]
+	diff, err := o.ChangeItems([]LineChange{
+		{LineID: a.LineID, Quantity: 3},
+		{LineID: b.LineID, Quantity: 0},
+		{SKU: "C", Quantity: 1, Price: usd(70), TaxClass: "food"},
+	}, false)
+	if err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	if len(o.Items) != 2 || o.Items[0].LineID != a.LineID || o.Items[0].LineTotal != usd(300) || o.Items[1].SKU != "C" || o.Items[1].LineID == uuid.Nil {
+		t.Fatalf("lines: %+v", o.Items)
+	}
+	if o.TotalAmount != usd(370) || o.SubtotalAmount != usd(370) || !o.DiscountAmount.IsZero() || o.Discounts != nil {
+		t.Fatalf("totals: total=%s subtotal=%s discount=%s", o.TotalAmount, o.SubtotalAmount, o.DiscountAmount)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].LineID != o.Items[1].LineID || len(diff.Removed) != 1 || diff.Removed[0].LineID != b.LineID ||
+		len(diff.Changed) != 1 || diff.Changed[0] != (QuantityChange{LineID: a.LineID, SKU: "A", From: 2, To: 3}) {
+		t.Fatalf("diff: %+v", diff)
+	}
+
+	// replacing drops every line that is not named
+	o = newOrder()
+	a = o.Items[0]
+	diff, err = o.ChangeItems([]LineChange{{LineID: a.LineID, SKU: "A", Quantity: 2}}, true)
+	if err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if len(o.Items) != 1 || len(diff.Removed) != 1 || len(diff.Changed) != 0 || len(diff.Added) != 0 || o.TotalAmount != usd(200) {
+		t.Fatalf("put: items=%+v diff=%+v", o.Items, diff)
+	}
+
+	// naming lines with their current quantities changes nothing
+	n := newOrder()
+	same := []LineChange{{LineID: n.Items[0].LineID, Quantity: 2}, {LineID: n.Items[1].LineID, Quantity: 1}}
+	for _, changes := range [][]LineChange{nil, same} {
+		if diff, err = n.ChangeItems(changes, false); err != nil || !diff.Empty() {
+			t.Fatalf("no-op: diff=%+v err=%v", diff, err)
+		}
+	}
+	if diff, err = n.ChangeItems(same, true); err != nil || !diff.Empty() {
+		t.Fatalf("no-op put: diff=%+v err=%v", diff, err)
+	}
+
+	var ve *ValidationError
+	for name, tc := range map[string]struct {
+		changes []LineChange
+		replace bool
+	}{
+		"unknown line":      {changes: []LineChange{{LineID: uuid.New(), Quantity: 1}}},
+		"sku mismatch":      {changes: []LineChange{{LineID: a.LineID, SKU: "B", Quantity: 1}}},
+		"changed twice":     {changes: []LineChange{{LineID: a.LineID, Quantity: 1}, {LineID: a.LineID, Quantity: 2}}},
+		"remove everything": {changes: []LineChange{{LineID: a.LineID, Quantity: 0}}},
+		"zero on replace":   {changes: []LineChange{{LineID: a.LineID, Quantity: 0}, {SKU: "C", Quantity: 1, Price: usd(1)}}, replace: true},
+		"new without sku":   {changes: []LineChange{{Quantity: 1, Price: usd(1)}}},
+		"wrong currency":    {changes: []LineChange{{SKU: "C", Quantity: 1, Price: eur(1)}}},
+	} {
+		if _, err := o.ChangeItems(tc.changes, tc.replace); !errors.As(err, &ve) {
+			t.Fatalf("%s: want ValidationError, got %v", name, err)
+		}
+	}
+
+	if err := o.MarkPaid(); err != nil {
+		t.Fatalf("pay: %v", err)
+	}
+	if _, err := o.ChangeItems([]LineChange{{LineID: a.LineID, Quantity: 5}}, false); !errors.Is(err, ErrNotModifiable) {
+		t.Fatalf("paid order: want ErrNotModifiable, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Money is an amount in the minor unit of its currency, e.g. cents for USD.
+// Arithmetic is checked: combining currencies or overflowing int64 is an
+// error instead of a silently wrong total.
+type Money struct {
+	Amount   int64  `json:"amount_minor"`
+	Currency string `json:"currency"`
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+func (m Money) Add(n Money) (Money, error) {
+	if m.Currency != n.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	if (n.Amount > 0 && m.Amount > math.MaxInt64-n.Amount) || (n.Amount < 0 && m.Amount < math.MinInt64-n.Amount) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: m.Amount + n.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(n Money) (Money, error) {
+	if n.Amount == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+	return m.Add(Money{Amount: -n.Amount, Currency: n.Currency})
+}
+
+// Mul multiplies m by a quantity.
+func (m Money) Mul(n int64) (Money, error) {
+	if m.Amount == 0 || n == 0 {
+		return Money{Amount: 0, Currency: m.Currency}, nil
+	}
+	r := m.Amount * n
+	if r/n != m.Amount || (m.Amount == -1 && n == math.MinInt64) || (n == -1 && m.Amount == math.MinInt64) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: r, Currency: m.Currency}, nil
+}
+
+// Allocate splits m in proportion to weights so that the parts add up to m
+// exactly. Minor units left over by rounding go to the parts with the largest
+// remainders, earlier parts first on ties. When all weights are zero m is
+// split evenly.
+func (m Money) Allocate(weights []int64) ([]Money, error) {
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("allocate %s: no weights", m)
+	}
+	var sum big.Int
+	for _, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("allocate %s: negative weight %d", m, w)
+		}
+		sum.Add(&sum, big.NewInt(w))
+	}
+	ws := weights
+	if sum.Sign() == 0 {
+		ws = make([]int64, len(weights))
+		for i := range ws {
+			ws[i] = 1
+		}
+		sum.SetInt64(int64(len(ws)))
+	}
+
+	total := big.NewInt(m.Amount)
+	total.Abs(total)
+	parts := make([]Money, len(ws))
+	rems := make([]*big.Int, len(ws))
+	given := new(big.Int)
+	for i, w := range ws {
+		q, r := new(big.Int).QuoRem(new(big.Int).Mul(total, big.NewInt(w)), &sum, new(big.Int))
+		parts[i] = Money{Amount: q.Int64(), Currency: m.Currency}
+		rems[i] = r
+		given.Add(given, q)
+	}
+	order := make([]int, len(ws))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return rems[order[a]].Cmp(rems[order[b]]) > 0 })
+	left := new(big.Int).Sub(total, given).Int64()
+	for _, i := range order[:left] {
+		parts[i].Amount++
+	}
+	if m.Amount < 0 {
+		for i := range parts {
+			parts[i].Amount = -parts[i].Amount
+		}
+	}
+
+	return parts, nil
+}
+
+// String formats m in major units, e.g. "12.50 USD" or "1500 JPY".
+func (m Money) String() string {
+	exp := 2
+	if c, ok := LookupCurrency(m.Currency); ok {
+		exp = c.Exponent
+	}
+	neg := m.Amount < 0
+	digits := new(big.Int).Abs(big.NewInt(m.Amount)).String()
+	if exp > 0 {
+		if len(digits) <= exp {
+			digits = strings.Repeat("0", exp-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+	}
+	if neg {
+		digits = "-" + digits
+	}
+	return digits + " " + m.Currency
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestMoneyCheckedArithmetic(t *testing.T) {
+	if got, err := usd(150).Add(usd(75)); err != nil || got != usd(225) {
+		t.Fatalf("add: %s %v", got, err)
+	}
+	if got, err := usd(150).Sub(usd(200)); err != nil || got != usd(-50) {
+		t.Fatalf("sub: %s %v", got, err)
+	}
+	if got, err := usd(125).Mul(3); err != nil || got != usd(375) {
+		t.Fatalf("mul: %s %v", got, err)
+	}
+	if _, err := usd(1).Add(eur(1)); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("add across currencies: want ErrCurrencyMismatch, got %v", err)
+	}
+	if _, err := usd(math.MaxInt64).Add(usd(1)); !errors.Is(err, ErrAmountOverflow) {
+		t.Fatalf("add overflow: want ErrAmountOverflow, got %v", err)
+	}
+	if _, err := usd(math.MinInt64).Sub(usd(1)); !errors.Is(err, ErrAmountOverflow) {
+		t.Fatalf("sub overflow: want ErrAmountOverflow, got %v", err)
+	}
+	if _, err := usd(math.MaxInt64 / 2).Mul(3); !errors.Is(err, ErrAmountOverflow) {
+		t.Fatalf("mul overflow: want ErrAmountOverflow, got %v", err)
+	}
+}
+
+func TestMoneyAllocateKeepsEveryMinorUnit(t *testing.T) {
+	cases := []struct {
+		m       Money
+		weights []int64
+		want    []int64
+	}{
+		{usd(100), []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{usd(100), []int64{1, 2, 2}, []int64{20, 40, 40}},
+		{usd(5), []int64{3, 7}, []int64{2, 3}}, // 1.5 and 3.5: tie goes to the first part
+		{usd(-100), []int64{1, 1, 1}, []int64{-34, -33, -33}},
+		{usd(10), []int64{0, 0}, []int64{5, 5}},
+		{usd(math.MaxInt64), []int64{math.MaxInt64, math.MaxInt64}, []int64{math.MaxInt64/2 + 1, math.MaxInt64 / 2}},
+	}
+	for _, c := range cases {
+		parts, err := c.m.Allocate(c.weights)
+		if err != nil {
+			t.Fatalf("allocate %s by %v: %v", c.m, c.weights, err)
+		}
+		got := make([]int64, len(parts))
+		for i, p := range parts {
+			got[i] = p.Amount
+			if p.Currency != c.m.Currency {
+				t.Fatalf("part currency %q", p.Currency)
+			}
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("allocate %s by %v: got %v want %v", c.m, c.weights, got, c.want)
+		}
+	}
+	if _, err := usd(1).Allocate([]int64{1, -1}); err == nil {
+		t.Fatal("negative weight should fail")
+	}
+}
+
+func TestMoneyFormatAndJSON(t *testing.T) {
+	for m, want := range map[Money]string{
+		usd(1250):             "12.50 USD",
+		usd(-5):               "-0.05 USD",
+		NewMoney(1500, "JPY"): "1500 JPY",
+		NewMoney(1234, "KWD"): "1.234 KWD",
+	} {
+		if got := m.String(); got != want {
+			t.Fatalf("String(%+v) = %q, want %q", m, got, want)
+		}
+	}
+
+	b, err := json.Marshal(usd(1250))
+	if err != nil || string(b) != `{"amount_minor":1250,"currency":"USD"}` {
+		t.Fatalf("json: %s %v", b, err)
+	}
+}
@@ -1,71 +1,163 @@
 package domain
 
 import (
-	"errors"
 	"github.com/google/uuid"
+	"slices"
 	"time"
 )
 
 type Status string
 
 const (
-	StatusCreated   Status = "created"
-	StatusPaid      Status = "paid"
-	StatusCancelled Status = "cancelled"
-	StatusShipped   Status = "shipped"
+	StatusCreated           Status = "created"
+	StatusPaid              Status = "paid"
+	StatusCancelled         Status = "cancelled"
+	StatusPartiallyShipped  Status = "partially_shipped"
+	StatusShipped           Status = "shipped"
+	StatusPartiallyRefunded Status = "partially_refunded"
+	StatusRefunded          Status = "refunded"
+	StatusOnHold            Status = "on_hold"
 )
 
+// OpenStatuses are the statuses of orders that still need payment or
+// shipping. Refunds do not change the status, so a partially refunded order
+// is open as long as its fulfilment status is.
+var OpenStatuses = []Status{StatusCreated, StatusOnHold, StatusPaid, StatusPartiallyShipped}
+
+// Open reports whether s is one of OpenStatuses.
+func (s Status) Open() bool {
+	return slices.Contains(OpenStatuses, s)
+}
+
+// Item is an order line. LineID is assigned by New and never changes, so
+// shipments, refunds and returns can reference individual lines.
 type Item struct {
-	SKU        string `json:"sku"`
-	Quantity   int    `json:"quantity"`
-	PriceMinor int64  `json:"price_minor"`
+	LineID          uuid.UUID `json:"line_id"`
+	SKU             string    `json:"sku"`
+	Quantity        int       `json:"quantity"`
+	Price           Money     `json:"price"`
+	LineTotal       Money     `json:"line_total"`
+	ShippedQuantity int       `json:"shipped_quantity"`
+	TaxClass        string    `json:"tax_class,omitempty"`
+	Tax             Money     `json:"tax"`
 }
 
 type Order struct {
-	ID          uuid.UUID `json:"id"`
-	CustomerID  uuid.UUID `json:"customer_id"`
-	Status      Status    `json:"status"`
-	Currency    string    `json:"currency"`
-	TotalAmount int64     `json:"total_amount"`
-	Items       []Item    `json:"items"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID         uuid.UUID `json:"id"`
+	CustomerID uuid.UUID `json:"customer_id"`
+	Status     Status    `json:"status"`
+	// HeldStatus is the status an on_hold order returns to once released.
+	HeldStatus Status `json:"held_status,omitempty"`
+	Holds      []Hold `json:"holds,omitempty"`
+	Currency   string `json:"currency"`
+	// SubtotalAmount is the net amount before tax, after discounts;
+	// TotalAmount is what the customer pays. All amounts are in Currency.
+	SubtotalAmount Money  `json:"subtotal_amount"`
+	TaxAmount      Money  `json:"tax_amount"`
+	TaxInclusive   bool   `json:"tax_inclusive"`
+	TotalAmount    Money  `json:"total_amount"`
+	Items          []Item `json:"items"`
+	// ShippingAddress and BillingAddress are optional and can only change
+	// while the order is created.
+	ShippingAddress *Address `json:"shipping_address,omitempty"`
+	BillingAddress  *Address `json:"billing_address,omitempty"`
+	// DiscountAmount is already taken off SubtotalAmount and TotalAmount.
+	Discounts      []Discount `json:"discounts,omitempty"`
+	DiscountAmount Money      `json:"discount_amount"`
+	// Reporting is TotalAmount in the reporting currency, converted at the
+	// rate of the time the order was created.
+	Reporting *ReportingAmount `json:"reporting,omitempty"`
+	// RefundedAmount is the sum of all refunds, never more than TotalAmount.
+	// RefundStatus is derived from it: partially_refunded or refunded. A
+	// partial refund leaves Status alone, a full one makes it refunded.
+	RefundedAmount Money     `json:"refunded_amount"`
+	RefundStatus   Status    `json:"refund_status,omitempty"`
+	Version        int64     `json:"version"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
-func New(customerID uuid.UUID, currency string, items []Item) (*Order, error) {
+func New(customerID uuid.UUID, currency string, items []Item, shipping, billing *Address) (*Order, error) {
 	if customerID == uuid.Nil {
-		return nil, errors.New("customerID is required")
+		return nil, &ValidationError{Msg: "customer_id is required"}
 	}
 	if currency == "" {
-		return nil, errors.New("currency is required")
+		return nil, &ValidationError{Msg: "currency is required"}
+	}
+	if _, ok := LookupCurrency(currency); !ok {
+		return nil, &ValidationError{Msg: "unknown currency " + currency}
 	}
 	if len(items) == 0 {
-		return nil, errors.New("at least one item required")
+		return nil, &ValidationError{Msg: "at least one item required"}
 	}
-	var total int64
-	for _, it := range items {
-		if it.SKU == "" || it.Quantity <= 0 || it.PriceMinor < 0 {
-			return nil, errors.New("invalid item")
+	if err := validateAddresses(shipping, billing); err != nil {
+		return nil, err
+	}
+	lines := make([]Item, len(items))
+	total := NewMoney(0, currency)
+	for i, it := range items {
+		if it.SKU == "" || it.Quantity <= 0 || it.Price.IsNegative() {
+			return nil, &ValidationError{Msg: "invalid item"}
+		}
+		if it.Price.Currency != currency {
+			return nil, ErrCurrencyMismatch
+		}
+		var err error
+		if it.LineTotal, err = it.Price.Mul(int64(it.Quantity)); err != nil {
+			return nil, err
 		}
-		total += int64(it.Quantity) * it.PriceMinor
+		if total, err = total.Add(it.LineTotal); err != nil {
+			return nil, err
+		}
+		// nothing of a new order has shipped, whatever the caller sent
+		it.LineID = uuid.New()
+		it.Tax = NewMoney(0, currency)
+		it.ShippedQuantity = 0
+		lines[i] = it
 	}
+	zero := NewMoney(0, currency)
 	now := time.Now().UTC()
 
 	return &Order{
-		ID:          uuid.New(),
-		CustomerID:  customerID,
-		Status:      StatusCreated,
-		Currency:    currency,
-		TotalAmount: total,
-		Items:       items,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		ID:              uuid.New(),
+		CustomerID:      customerID,
+		Status:          StatusCreated,
+		Currency:        currency,
+		SubtotalAmount:  total,
+		TaxAmount:       zero,
+		TotalAmount:     total,
+		DiscountAmount:  zero,
+		RefundedAmount:  zero,
+		Items:           lines,
+		ShippingAddress: shipping,
+		BillingAddress:  billing,
+		Version:         1,
+		CreatedAt:       now,
+		UpdatedAt:       now,
 	}, nil
 }
 
+// Transition moves the order to the target status through the matching
+// state-machine method, so callers can never bypass its rules.
+func (o *Order) Transition(to Status) error {
+	switch to {
+	case StatusPaid:
+		return o.MarkPaid()
+	case StatusCancelled:
+		return o.Cancel()
+	case StatusShipped:
+		return o.MarkShipped()
+	default:
+		return &TransitionError{From: o.Status, To: to, Reason: "unsupported target status"}
+	}
+}
+
 func (o *Order) MarkPaid() error {
+	if o.OnHold() {
+		return &TransitionError{From: o.Status, To: StatusPaid, Reason: "order is on hold"}
+	}
 	if o.Status != StatusCreated {
-		return errors.New("only created orders can be paid")
+		return &TransitionError{From: o.Status, To: StatusPaid, Reason: "only created orders can be paid"}
 	}
 	o.Status = StatusPaid
 	o.UpdatedAt = time.Now().UTC()
@@ -73,25 +165,70 @@ func (o *Order) MarkPaid() error {
 	return nil
 }
 
+// Cancel stops the order. Held orders can be cancelled if the status they
+// were held in allows it; their holds end with the order.
 func (o *Order) Cancel() error {
-	if o.Status == StatusShipped {
-		return errors.New("cannot cancel shipped order")
+	from := o.Status
+	if o.OnHold() {
+		from = o.HeldStatus
+	}
+	if from == StatusShipped || from == StatusPartiallyShipped {
+		return &TransitionError{From: o.Status, To: StatusCancelled, Reason: "cannot cancel shipped order"}
+	}
+	if from == StatusRefunded || o.RefundedAmount.Amount > 0 {
+		return &TransitionError{From: o.Status, To: StatusCancelled, Reason: "cannot cancel refunded order"}
 	}
 	if o.Status == StatusCancelled {
 		return nil
 	}
-	o.Status = StatusCancelled
+	o.Status, o.HeldStatus, o.Holds = StatusCancelled, "", nil
 	o.UpdatedAt = time.Now().UTC()
 
 	return nil
 }
 
+// MarkShipped ships everything that is still outstanding in one go.
 func (o *Order) MarkShipped() error {
-	if o.Status != StatusPaid {
-		return errors.New("only paid orders can be shipped")
+	if o.OnHold() {
+		return &TransitionError{From: o.Status, To: StatusShipped, Reason: "order is on hold"}
+	}
+	if !o.shippable() {
+		return &TransitionError{From: o.Status, To: StatusShipped, Reason: "only paid orders can be shipped"}
+	}
+	for i := range o.Items {
+		o.Items[i].ShippedQuantity = o.Items[i].Quantity
 	}
 	o.Status = StatusShipped
 	o.UpdatedAt = time.Now().UTC()
 
 	return nil
 }
+
+// modifiable reports whether items, addresses and amounts may still change:
+// the order has not been paid, though it may be on hold.
+func (o *Order) modifiable() bool {
+	return o.Status == StatusCreated || (o.OnHold() && o.HeldStatus == StatusCreated)
+}
+
+// shippable reports whether goods may still leave the warehouse: the order
+// is paid and some line is not fully shipped. A partial refund (e.g. for an
+// out-of-stock line) does not stop the rest shipping.
+func (o *Order) shippable() bool {
+	if o.Status != StatusPaid && o.Status != StatusPartiallyShipped {
+		return false
+	}
+	for _, it := range o.Items {
+		if it.ShippedQuantity < it.Quantity {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Order) lineIndex() map[uuid.UUID]int {
+	idx := make(map[uuid.UUID]int, len(o.Items))
+	for i, it := range o.Items {
+		idx[it.LineID] = i
+	}
+	return idx
+}
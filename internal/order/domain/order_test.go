@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"errors"
 	"github.com/google/uuid"
 	"testing"
 )
@@ -8,23 +9,45 @@ import (
 func TestNewOrderComputesTotalAndValidates(t *testing.T) {
 	cid := uuid.New()
 	o, err := New(cid, "USD", []Item{
-		{SKU: "A", Quantity: 2, PriceMinor: 150}, // 300
-		{SKU: "B", Quantity: 1, PriceMinor: 125}, // 125
-	})
+		{SKU: "A", Quantity: 2, Price: usd(150)}, // 300
+		{SKU: "B", Quantity: 1, Price: usd(125)}, // 125
+	}, nil, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if got, want := o.TotalAmount, int64(425); got != want {
-		t.Fatalf("total: got %d want %d", got, want)
+	if got, want := o.TotalAmount, usd(425); got != want {
+		t.Fatalf("total: got %s want %s", got, want)
 	}
 	if o.Status != StatusCreated {
 		t.Fatalf("status: got %s want created", o.Status)
 	}
+	if o.Items[0].LineTotal.Amount != 300 || o.Items[1].LineTotal.Amount != 125 {
+		t.Fatalf("line totals: got %d, %d", o.Items[0].LineTotal.Amount, o.Items[1].LineTotal.Amount)
+	}
+	if o.Items[0].LineID == uuid.Nil || o.Items[0].LineID == o.Items[1].LineID {
+		t.Fatalf("line ids must be set and unique: %s, %s", o.Items[0].LineID, o.Items[1].LineID)
+	}
+}
+
+func TestNewIgnoresShippedQuantity(t *testing.T) {
+	o, err := New(uuid.New(), "USD", []Item{{SKU: "A", Quantity: 2, ShippedQuantity: 2, Price: usd(150)}}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := o.Items[0].ShippedQuantity; got != 0 {
+		t.Fatalf("shipped quantity: got %d want 0", got)
+	}
+	if err := o.MarkPaid(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.MarkShipped(); err != nil || o.Items[0].ShippedQuantity != 2 {
+		t.Fatalf("ship: err=%v shipped=%d", err, o.Items[0].ShippedQuantity)
+	}
 }
 
 func TestStatusTransitions(t *testing.T) {
 	cid := uuid.New()
-	o, err := New(cid, "USD", []Item{{SKU: "A", Quantity: 1, PriceMinor: 100}})
+	o, err := New(cid, "USD", []Item{{SKU: "A", Quantity: 1, Price: usd(100)}}, nil, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -38,3 +61,60 @@ func TestStatusTransitions(t *testing.T) {
 		t.Fatalf("cancel after shipped should fail")
 	}
 }
+
+func TestTransitionRejectsIllegalMoves(t *testing.T) {
+	o, err := New(uuid.New(), "USD", []Item{{SKU: "A", Quantity: 1, Price: usd(100)}}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var te *TransitionError
+	if err := o.Transition(StatusShipped); !errors.As(err, &te) {
+		t.Fatalf("ship before pay: want TransitionError, got %v", err)
+	}
+	if err := o.Transition(StatusPaid); err != nil {
+		t.Fatalf("pay: %v", err)
+	}
+	if err := o.Transition(StatusShipped); err != nil {
+		t.Fatalf("ship: %v", err)
+	}
+	if err := o.Transition(StatusPaid); !errors.As(err, &te) {
+		t.Fatalf("pay after ship: want TransitionError, got %v", err)
+	}
+	if te.From != StatusShipped || te.To != StatusPaid {
+		t.Fatalf("transition error: got %s -> %s", te.From, te.To)
+	}
+	if o.Status != StatusShipped {
+		t.Fatalf("status changed on rejected transition: %s", o.Status)
+	}
+}
+
+func TestCancelIsIdempotent(t *testing.T) {
+	o, err := New(uuid.New(), "USD", []Item{{SKU: "A", Quantity: 1, Price: usd(100)}}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Transition(StatusCancelled); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if err := o.Transition(StatusCancelled); err != nil {
+		t.Fatalf("second cancel: %v", err)
+	}
+}
+
+func usd(amount int64) Money { return NewMoney(amount, "USD") }
+func eur(amount int64) Money { return NewMoney(amount, "EUR") }
+
+func TestNewRejectsOverflowingTotals(t *testing.T) {
+	cid := uuid.New()
+	if _, err := New(cid, "USD", []Item{{SKU: "A", Quantity: 1 << 30, Price: usd(1 << 40)}}, nil, nil); !errors.Is(err, ErrAmountOverflow) {
+		t.Fatalf("line overflow: want ErrAmountOverflow, got %v", err)
+	}
+	big := usd(1 << 62)
+	if _, err := New(cid, "USD", []Item{{SKU: "A", Quantity: 1, Price: big}, {SKU: "B", Quantity: 1, Price: big}}, nil, nil); !errors.Is(err, ErrAmountOverflow) {
+		t.Fatalf("total overflow: want ErrAmountOverflow, got %v", err)
+	}
+	if _, err := New(cid, "USD", []Item{{SKU: "A", Quantity: 1, Price: eur(100)}}, nil, nil); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("price in another currency: want ErrCurrencyMismatch, got %v", err)
+	}
+}
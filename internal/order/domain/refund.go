@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Refund is money paid back to the customer for an order. LineIDs optionally
+// point at the lines the refund is for; the amount is authoritative.
+type Refund struct {
+	ID        uuid.UUID   `json:"id"`
+	OrderID   uuid.UUID   `json:"order_id"`
+	Amount    Money       `json:"amount"`
+	LineIDs   []uuid.UUID `json:"line_ids,omitempty"`
+	Reason    string      `json:"reason,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// RefundableAmount is what can still be refunded.
+func (o *Order) RefundableAmount() Money {
+	return NewMoney(o.TotalAmount.Amount-o.RefundedAmount.Amount, o.Currency)
+}
+
+// RefundStatusOf derives the refund status from the refunded and total
+// amounts: empty before any refund, partially_refunded or refunded.
+func RefundStatusOf(refunded, total Money) Status {
+	switch {
+	case refunded.Amount <= 0:
+		return ""
+	case refunded.Amount < total.Amount:
+		return StatusPartiallyRefunded
+	default:
+		return StatusRefunded
+	}
+}
+
+// Refund records a refund of amount. A partial refund keeps the fulfilment
+// status, so the rest of the order can still ship; once the whole total has
+// been paid back the order becomes refunded.
+func (o *Order) Refund(amount Money, lineIDs []uuid.UUID, reason string) (*Refund, error) {
+	switch o.Status {
+	case StatusPaid, StatusPartiallyShipped, StatusShipped:
+	default:
+		return nil, &TransitionError{From: o.Status, To: StatusRefunded, Reason: "only paid orders can be refunded"}
+	}
+	if amount.Currency != o.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+	if amount.Amount <= 0 {
+		return nil, &ValidationError{Msg: "refund amount must be positive"}
+	}
+	if refundable := o.RefundableAmount(); amount.Amount > refundable.Amount {
+		return nil, &ValidationError{Msg: fmt.Sprintf("refund exceeds refundable amount %s", refundable)}
+	}
+	idx := o.lineIndex()
+	for _, id := range lineIDs {
+		if _, ok := idx[id]; !ok {
+			return nil, &ValidationError{Msg: fmt.Sprintf("unknown line %s", id)}
+		}
+	}
+
+	o.RefundedAmount.Amount += amount.Amount
+	o.RefundStatus = RefundStatusOf(o.RefundedAmount, o.TotalAmount)
+	if o.RefundStatus == StatusRefunded {
+		o.Status = StatusRefunded
+	}
+	now := time.Now().UTC()
+	o.UpdatedAt = now
+
+	return &Refund{
+		ID:        uuid.New(),
+		OrderID:   o.ID,
+		Amount:    amount,
+		LineIDs:   lineIDs,
+		Reason:    reason,
+		CreatedAt: now,
+	}, nil
+}
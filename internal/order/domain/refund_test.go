@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRefundNeverExceedsTotal(t *testing.T) {
+	o := paidOrder(t, Item{SKU: "A", Quantity: 2, Price: usd(500)})
+
+	if _, err := o.Refund(usd(300), []uuid.UUID{o.Items[0].LineID}, "damaged"); err != nil {
+		t.Fatalf("partial refund: %v", err)
+	}
+	if o.Status != StatusPaid || o.RefundStatus != StatusPartiallyRefunded || o.RefundedAmount.Amount != 300 {
+		t.Fatalf("after partial refund: status=%s refund status=%s refunded=%d", o.Status, o.RefundStatus, o.RefundedAmount.Amount)
+	}
+
+	var ve *ValidationError
+	if _, err := o.Refund(usd(701), nil, ""); !errors.As(err, &ve) {
+		t.Fatalf("over-refund: want ValidationError, got %v", err)
+	}
+	if _, err := o.Refund(usd(0), nil, ""); !errors.As(err, &ve) {
+		t.Fatalf("zero refund: want ValidationError, got %v", err)
+	}
+	if _, err := o.Refund(usd(10), []uuid.UUID{uuid.New()}, ""); !errors.As(err, &ve) {
+		t.Fatalf("unknown line: want ValidationError, got %v", err)
+	}
+	if o.RefundedAmount.Amount != 300 {
+		t.Fatalf("rejected refunds changed the refunded amount: %d", o.RefundedAmount.Amount)
+	}
+
+	if _, err := o.Refund(usd(700), nil, ""); err != nil {
+		t.Fatalf("final refund: %v", err)
+	}
+	if o.Status != StatusRefunded || o.RefundStatus != StatusRefunded || o.RefundableAmount().Amount != 0 {
+		t.Fatalf("after full refund: status=%s refundable=%d", o.Status, o.RefundableAmount().Amount)
+	}
+	if err := o.Cancel(); err == nil {
+		t.Fatalf("cancel after refund should fail")
+	}
+}
+
+func TestRefundRequiresPayment(t *testing.T) {
+	o, err := New(uuid.New(), "USD", []Item{{SKU: "A", Quantity: 1, Price: usd(100)}}, nil, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	var te *TransitionError
+	if _, err := o.Refund(usd(100), nil, ""); !errors.As(err, &te) {
+		t.Fatalf("want TransitionError, got %v", err)
+	}
+}
+
+func TestPartialRefundAfterShippingKeepsOrderShipped(t *testing.T) {
+	o := paidOrder(t, Item{SKU: "A", Quantity: 2, Price: usd(500)})
+	if err := o.MarkShipped(); err != nil {
+		t.Fatalf("ship: %v", err)
+	}
+	if _, err := o.Refund(usd(100), nil, "late"); err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if o.Status != StatusShipped || o.RefundStatus != StatusPartiallyRefunded {
+		t.Fatalf("after refund: status=%s refund status=%s", o.Status, o.RefundStatus)
+	}
+
+	var te *TransitionError
+	if err := o.MarkShipped(); !errors.As(err, &te) {
+		t.Fatalf("shipping again: want TransitionError, got %v", err)
+	}
+	if _, err := o.Ship([]ShipmentLine{{LineID: o.Items[0].LineID, Quantity: 1}}, "ups", "1Z1"); !errors.As(err, &te) {
+		t.Fatalf("parcel after full shipment: want TransitionError, got %v", err)
+	}
+	if _, err := o.PlaceHold(HoldStock, "", "ops"); !errors.As(err, &te) {
+		t.Fatalf("hold after full shipment: want TransitionError, got %v", err)
+	}
+}
+
+func TestPartialRefundThenShipRest(t *testing.T) {
+	o := paidOrder(t,
+		Item{SKU: "A", Quantity: 1, Price: usd(500)},
+		Item{SKU: "B", Quantity: 1, Price: usd(300)},
+	)
+	if _, err := o.Ship([]ShipmentLine{{LineID: o.Items[0].LineID, Quantity: 1}}, "ups", "1Z1"); err != nil {
+		t.Fatalf("first parcel: %v", err)
+	}
+	if _, err := o.Refund(usd(50), nil, "late"); err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if o.Status != StatusPartiallyShipped || o.RefundStatus != StatusPartiallyRefunded || !o.Status.Open() {
+		t.Fatalf("after refund: status=%s refund status=%s", o.Status, o.RefundStatus)
+	}
+	if _, err := o.Ship([]ShipmentLine{{LineID: o.Items[1].LineID, Quantity: 1}}, "ups", "1Z2"); err != nil {
+		t.Fatalf("second parcel: %v", err)
+	}
+	if o.Status != StatusShipped || o.RefundStatus != StatusPartiallyRefunded || o.RefundedAmount.Amount != 50 || o.Status.Open() {
+		t.Fatalf("after shipping the rest: status=%s refund status=%s refunded=%d", o.Status, o.RefundStatus, o.RefundedAmount.Amount)
+	}
+	if err := o.Cancel(); err == nil {
+		t.Fatalf("cancel after refund should fail")
+	}
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// ReportingAmount is an order amount converted for finance reporting. Rate is
+// the decimal exchange rate from the order currency to the reporting
+// currency, kept as text so it round-trips exactly.
+type ReportingAmount struct {
+	Amount Money     `json:"amount"`
+	Rate   string    `json:"rate"`
+	RateAt time.Time `json:"rate_at"`
+}
@@ -0,0 +1,167 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReturnStatus string
+
+const (
+	ReturnRequested ReturnStatus = "requested"
+	ReturnApproved  ReturnStatus = "approved"
+	ReturnRejected  ReturnStatus = "rejected"
+	ReturnReceived  ReturnStatus = "received"
+	ReturnRefunded  ReturnStatus = "refunded"
+)
+
+type ReturnLine struct {
+	LineID   uuid.UUID `json:"line_id"`
+	Quantity int       `json:"quantity"`
+}
+
+// Return is a return merchandise authorization for shipped lines of an order.
+// It moves requested -> approved -> received -> refunded, or requested ->
+// rejected.
+type Return struct {
+	ID        uuid.UUID    `json:"id"`
+	OrderID   uuid.UUID    `json:"order_id"`
+	Status    ReturnStatus `json:"status"`
+	Lines     []ReturnLine `json:"lines"`
+	Reason    string       `json:"reason,omitempty"`
+	RefundID  *uuid.UUID   `json:"refund_id,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// RequestReturn opens a return for shipped quantities of the given lines.
+// existing are the order's other returns; units in any of them that was not
+// rejected cannot be returned again.
+func (o *Order) RequestReturn(existing []*Return, lines []ReturnLine, reason string) (*Return, error) {
+	switch o.Status {
+	case StatusPartiallyShipped, StatusShipped:
+	default:
+		return nil, &ValidationError{Msg: fmt.Sprintf("order in status %s does not accept returns", o.Status)}
+	}
+	if len(lines) == 0 {
+		return nil, &ValidationError{Msg: "return must contain at least one line"}
+	}
+
+	idx := o.lineIndex()
+	returned := make(map[int]int)
+	for _, r := range existing {
+		if r.Status == ReturnRejected {
+			continue
+		}
+		for _, l := range r.Lines {
+			if i, ok := idx[l.LineID]; ok {
+				returned[i] += l.Quantity
+			}
+		}
+	}
+	for _, l := range lines {
+		i, ok := idx[l.LineID]
+		if !ok {
+			return nil, &ValidationError{Msg: fmt.Sprintf("unknown line %s", l.LineID)}
+		}
+		if l.Quantity <= 0 {
+			return nil, &ValidationError{Msg: fmt.Sprintf("line %s: quantity must be positive", l.LineID)}
+		}
+		if left := o.Items[i].ShippedQuantity - returned[i]; l.Quantity > left {
+			return nil, &ValidationError{Msg: fmt.Sprintf("line %s: only %d left to return", l.LineID, left)}
+		}
+		returned[i] += l.Quantity
+	}
+	now := time.Now().UTC()
+
+	return &Return{
+		ID:        uuid.New(),
+		OrderID:   o.ID,
+		Status:    ReturnRequested,
+		Lines:     lines,
+		Reason:    reason,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// ReturnAmount is what a return is worth at the order's line prices, scaled
+// by what the customer paid relative to the items total (discounts, tax
+// added on top), capped at what is still refundable.
+func (o *Order) ReturnAmount(r *Return) (Money, error) {
+	idx := o.lineIndex()
+	amount := NewMoney(0, o.Currency)
+	for _, l := range r.Lines {
+		i, ok := idx[l.LineID]
+		if !ok {
+			continue
+		}
+		line, err := o.Items[i].Price.Mul(int64(l.Quantity))
+		if err != nil {
+			return Money{}, err
+		}
+		if amount, err = amount.Add(line); err != nil {
+			return Money{}, err
+		}
+	}
+	if items := o.ItemsTotal(); o.TotalAmount != items && items.Amount > 0 {
+		// the return's share of what was paid
+		shares, err := o.TotalAmount.Allocate([]int64{amount.Amount, max(items.Amount-amount.Amount, 0)})
+		if err != nil {
+			return Money{}, err
+		}
+		amount = shares[0]
+	}
+
+	if refundable := o.RefundableAmount(); refundable.Amount < amount.Amount {
+		return refundable, nil
+	}
+	return amount, nil
+}
+
+// LineIDs lists the order lines the return covers.
+func (r *Return) LineIDs() []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(r.Lines))
+	seen := make(map[uuid.UUID]bool, len(r.Lines))
+	for _, l := range r.Lines {
+		if !seen[l.LineID] {
+			seen[l.LineID] = true
+			ids = append(ids, l.LineID)
+		}
+	}
+	return ids
+}
+
+func (r *Return) Approve() error {
+	return r.transition(ReturnRequested, ReturnApproved)
+}
+
+func (r *Return) Reject() error {
+	return r.transition(ReturnRequested, ReturnRejected)
+}
+
+// Receive records that the goods arrived back at the warehouse.
+func (r *Return) Receive() error {
+	return r.transition(ReturnApproved, ReturnReceived)
+}
+
+// MarkRefunded links the refund paid for a received return. refundID is nil
+// when nothing was left to refund on the order.
+func (r *Return) MarkRefunded(refundID *uuid.UUID) error {
+	if err := r.transition(ReturnReceived, ReturnRefunded); err != nil {
+		return err
+	}
+	r.RefundID = refundID
+	return nil
+}
+
+func (r *Return) transition(from, to ReturnStatus) error {
+	if r.Status != from {
+		return &ReturnTransitionError{From: r.Status, To: to}
+	}
+	r.Status = to
+	r.UpdatedAt = time.Now().UTC()
+	return nil
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReturnOnlyShippedQuantities(t *testing.T) {
+	o := paidOrder(t,
+		Item{SKU: "A", Quantity: 3, Price: usd(100)},
+		Item{SKU: "B", Quantity: 1, Price: usd(50)},
+	)
+	a, b := o.Items[0].LineID, o.Items[1].LineID
+
+	var ve *ValidationError
+	if _, err := o.RequestReturn(nil, []ReturnLine{{LineID: a, Quantity: 1}}, ""); !errors.As(err, &ve) {
+		t.Fatalf("return before shipping: want ValidationError, got %v", err)
+	}
+	if _, err := o.Ship([]ShipmentLine{{LineID: a, Quantity: 2}}, "ups", "1Z1"); err != nil {
+		t.Fatalf("ship: %v", err)
+	}
+
+	first, err := o.RequestReturn(nil, []ReturnLine{{LineID: a, Quantity: 1}}, "too big")
+	if err != nil {
+		t.Fatalf("first return: %v", err)
+	}
+	if first.Status != ReturnRequested || first.OrderID != o.ID {
+		t.Fatalf("first return: status=%s order=%s", first.Status, first.OrderID)
+	}
+	if _, err := o.RequestReturn([]*Return{first}, []ReturnLine{{LineID: a, Quantity: 2}}, ""); !errors.As(err, &ve) {
+		t.Fatalf("returning more than shipped: want ValidationError, got %v", err)
+	}
+	if _, err := o.RequestReturn(nil, []ReturnLine{{LineID: b, Quantity: 1}}, ""); !errors.As(err, &ve) {
+		t.Fatalf("returning unshipped line: want ValidationError, got %v", err)
+	}
+
+	if err := first.Reject(); err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	if _, err := o.RequestReturn([]*Return{first}, []ReturnLine{{LineID: a, Quantity: 2}}, ""); err != nil {
+		t.Fatalf("rejected returns should free their units: %v", err)
+	}
+}
+
+func TestReturnLifecycle(t *testing.T) {
+	o := paidOrder(t, Item{SKU: "A", Quantity: 2, Price: usd(500)})
+	if err := o.MarkShipped(); err != nil {
+		t.Fatalf("ship: %v", err)
+	}
+	r, err := o.RequestReturn(nil, []ReturnLine{{LineID: o.Items[0].LineID, Quantity: 1}}, "")
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+
+	var te *ReturnTransitionError
+	if err := r.Receive(); !errors.As(err, &te) {
+		t.Fatalf("receive before approval: want ReturnTransitionError, got %v", err)
+	}
+	if err := r.Approve(); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if err := r.Reject(); !errors.As(err, &te) {
+		t.Fatalf("reject after approval: want ReturnTransitionError, got %v", err)
+	}
+	if err := r.Receive(); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	amount, err := o.ReturnAmount(r)
+	if err != nil || amount != usd(500) {
+		t.Fatalf("return amount = %s, want 500 (%v)", amount, err)
+	}
+	rf, err := o.Refund(amount, r.LineIDs(), "return")
+	if err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if err := r.MarkRefunded(&rf.ID); err != nil {
+		t.Fatalf("mark refunded: %v", err)
+	}
+	if r.Status != ReturnRefunded || r.RefundID == nil || *r.RefundID != rf.ID {
+		t.Fatalf("after refund: status=%s refund=%v", r.Status, r.RefundID)
+	}
+
+	if _, err := o.Refund(usd(600), nil, ""); err == nil {
+		t.Fatalf("refund beyond total should fail")
+	}
+	if got, err := o.ReturnAmount(&Return{Lines: []ReturnLine{{LineID: o.Items[0].LineID, Quantity: 2}}}); err != nil || got != usd(500) {
+		t.Fatalf("return amount should be capped at refundable, got %s (%v)", got, err)
+	}
+}
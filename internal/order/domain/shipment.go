@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ShipmentLine struct {
+	LineID   uuid.UUID `json:"line_id"`
+	Quantity int       `json:"quantity"`
+}
+
+// Shipment is one parcel sent for an order. An order may be shipped in
+// several shipments, each covering some quantity of some lines.
+type Shipment struct {
+	ID             uuid.UUID      `json:"id"`
+	OrderID        uuid.UUID      `json:"order_id"`
+	Lines          []ShipmentLine `json:"lines"`
+	Carrier        string         `json:"carrier"`
+	TrackingNumber string         `json:"tracking_number"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// Ship records a parcel for the given lines. The order becomes shipped once
+// every line is fully shipped and partially_shipped until then.
+func (o *Order) Ship(lines []ShipmentLine, carrier, trackingNumber string) (*Shipment, error) {
+	if o.OnHold() {
+		return nil, &TransitionError{From: o.Status, To: StatusShipped, Reason: "order is on hold"}
+	}
+	if !o.shippable() {
+		return nil, &TransitionError{From: o.Status, To: StatusShipped, Reason: "only paid orders can be shipped"}
+	}
+	if carrier == "" || trackingNumber == "" {
+		return nil, &ValidationError{Msg: "carrier and tracking number are required"}
+	}
+	if len(lines) == 0 {
+		return nil, &ValidationError{Msg: "shipment must contain at least one line"}
+	}
+
+	idx := o.lineIndex()
+	shipping := make(map[int]int, len(lines))
+	for _, l := range lines {
+		i, ok := idx[l.LineID]
+		if !ok {
+			return nil, &ValidationError{Msg: fmt.Sprintf("unknown line %s", l.LineID)}
+		}
+		if l.Quantity <= 0 {
+			return nil, &ValidationError{Msg: fmt.Sprintf("line %s: quantity must be positive", l.LineID)}
+		}
+		shipping[i] += l.Quantity
+		if it := o.Items[i]; it.ShippedQuantity+shipping[i] > it.Quantity {
+			return nil, &ValidationError{Msg: fmt.Sprintf("line %s: only %d left to ship", l.LineID, it.Quantity-it.ShippedQuantity)}
+		}
+	}
+	for i, q := range shipping {
+		o.Items[i].ShippedQuantity += q
+	}
+
+	o.Status = StatusShipped
+	for _, it := range o.Items {
+		if it.ShippedQuantity < it.Quantity {
+			o.Status = StatusPartiallyShipped
+			break
+		}
+	}
+	now := time.Now().UTC()
+	o.UpdatedAt = now
+
+	return &Shipment{
+		ID:             uuid.New(),
+		OrderID:        o.ID,
+		Lines:          lines,
+		Carrier:        carrier,
+		TrackingNumber: trackingNumber,
+		CreatedAt:      now,
+	}, nil
+}
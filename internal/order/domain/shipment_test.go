@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func paidOrder(t *testing.T, items ...Item) *Order {
+	t.Helper()
+	o, err := New(uuid.New(), "USD", items, nil, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := o.MarkPaid(); err != nil {
+		t.Fatalf("pay: %v", err)
+	}
+	return o
+}
+
+func TestShipInParcels(t *testing.T) {
+	o := paidOrder(t,
+		Item{SKU: "A", Quantity: 3, Price: usd(100)},
+		Item{SKU: "B", Quantity: 1, Price: usd(50)},
+	)
+	a, b := o.Items[0].LineID, o.Items[1].LineID
+
+	sh, err := o.Ship([]ShipmentLine{{LineID: a, Quantity: 2}}, "ups", "1Z1")
+	if err != nil {
+		t.Fatalf("first parcel: %v", err)
+	}
+	if sh.OrderID != o.ID || o.Status != StatusPartiallyShipped || o.Items[0].ShippedQuantity != 2 {
+		t.Fatalf("after first parcel: status=%s shipped=%d", o.Status, o.Items[0].ShippedQuantity)
+	}
+	if err := o.Cancel(); err == nil {
+		t.Fatalf("cancel after partial shipment should fail")
+	}
+
+	var ve *ValidationError
+	if _, err := o.Ship([]ShipmentLine{{LineID: a, Quantity: 2}}, "ups", "1Z2"); !errors.As(err, &ve) {
+		t.Fatalf("overship: want ValidationError, got %v", err)
+	}
+	if _, err := o.Ship([]ShipmentLine{{LineID: uuid.New(), Quantity: 1}}, "ups", "1Z2"); !errors.As(err, &ve) {
+		t.Fatalf("unknown line: want ValidationError, got %v", err)
+	}
+	if o.Items[0].ShippedQuantity != 2 {
+		t.Fatalf("rejected parcel changed shipped quantity: %d", o.Items[0].ShippedQuantity)
+	}
+
+	if _, err := o.Ship([]ShipmentLine{{LineID: a, Quantity: 1}, {LineID: b, Quantity: 1}}, "dhl", "JD01"); err != nil {
+		t.Fatalf("last parcel: %v", err)
+	}
+	if o.Status != StatusShipped {
+		t.Fatalf("status: got %s want shipped", o.Status)
+	}
+}
+
+func TestShipRequiresPaidOrder(t *testing.T) {
+	o, err := New(uuid.New(), "USD", []Item{{SKU: "A", Quantity: 1, Price: usd(100)}}, nil, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	var te *TransitionError
+	if _, err := o.Ship([]ShipmentLine{{LineID: o.Items[0].LineID, Quantity: 1}}, "ups", "1Z1"); !errors.As(err, &te) {
+		t.Fatalf("want TransitionError, got %v", err)
+	}
+}
+
+func TestMarkShippedShipsRemainder(t *testing.T) {
+	o := paidOrder(t, Item{SKU: "A", Quantity: 2, Price: usd(100)})
+	if _, err := o.Ship([]ShipmentLine{{LineID: o.Items[0].LineID, Quantity: 1}}, "ups", "1Z1"); err != nil {
+		t.Fatalf("ship: %v", err)
+	}
+	if err := o.MarkShipped(); err != nil {
+		t.Fatalf("mark shipped: %v", err)
+	}
+	if o.Items[0].ShippedQuantity != 2 || o.Status != StatusShipped {
+		t.Fatalf("got status=%s shipped=%d", o.Status, o.Items[0].ShippedQuantity)
+	}
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// NetLineTotals is the amount of every line after its share of the order
+// discounts, in item order. Discounts are allocated in proportion to line
+// totals.
+func (o *Order) NetLineTotals() ([]Money, error) {
+	weights := make([]int64, len(o.Items))
+	for i, it := range o.Items {
+		weights[i] = it.LineTotal.Amount
+	}
+	shares, err := o.DiscountAmount.Allocate(weights)
+	if err != nil {
+		return nil, err
+	}
+	net := make([]Money, len(o.Items))
+	for i, it := range o.Items {
+		if net[i], err = it.LineTotal.Sub(shares[i]); err != nil {
+			return nil, err
+		}
+	}
+	return net, nil
+}
+
+// ApplyTax records the tax of every line, in item order. With inclusive
+// prices the tax is part of the discounted items total; otherwise it is
+// added on top.
+func (o *Order) ApplyTax(lineTaxes []Money, inclusive bool) error {
+	if !o.modifiable() {
+		return ErrNotModifiable
+	}
+	if len(lineTaxes) != len(o.Items) {
+		return fmt.Errorf("got tax for %d lines, order has %d", len(lineTaxes), len(o.Items))
+	}
+	tax := NewMoney(0, o.Currency)
+	for _, t := range lineTaxes {
+		if t.IsNegative() {
+			return &ValidationError{Msg: "negative tax"}
+		}
+		var err error
+		if tax, err = tax.Add(t); err != nil {
+			return err
+		}
+	}
+
+	net, err := o.ItemsTotal().Sub(o.DiscountAmount)
+	if err != nil {
+		return err
+	}
+	subtotal, total := net, net
+	if inclusive {
+		subtotal, err = net.Sub(tax)
+	} else {
+		total, err = net.Add(tax)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, t := range lineTaxes {
+		o.Items[i].Tax = t
+	}
+	o.TaxAmount = tax
+	o.TaxInclusive = inclusive
+	o.SubtotalAmount, o.TotalAmount = subtotal, total
+	o.UpdatedAt = time.Now().UTC()
+	return nil
+}
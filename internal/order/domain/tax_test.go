@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNetLineTotalsSpreadDiscount(t *testing.T) {
+	o, err := New(uuid.New(), "USD", []Item{
+		{SKU: "A", Quantity: 1, Price: usd(100)},
+		{SKU: "B", Quantity: 1, Price: usd(100)},
+		{SKU: "C", Quantity: 1, Price: usd(100)},
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := o.ApplyDiscounts([]Discount{{Code: "X", Amount: usd(100)}}); err != nil {
+		t.Fatalf("discount: %v", err)
+	}
+	got, err := o.NetLineTotals()
+	if err != nil {
+		t.Fatalf("net lines: %v", err)
+	}
+	if want := []Money{usd(66), usd(67), usd(67)}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("net lines: got %v want %v", got, want)
+	}
+}
+
+func TestApplyTax(t *testing.T) {
+	newOrder := func() *Order {
+		o, err := New(uuid.New(), "EUR", []Item{{SKU: "A", Quantity: 2, Price: eur(595)}}, nil, nil)
+		if err != nil {
+			t.Fatalf("new: %v", err)
+		}
+		return o
+	}
+
+	ex := newOrder()
+	if err := ex.ApplyTax([]Money{eur(226)}, false); err != nil {
+		t.Fatalf("exclusive: %v", err)
+	}
+	if ex.SubtotalAmount.Amount != 1190 || ex.TaxAmount.Amount != 226 || ex.TotalAmount.Amount != 1416 || ex.Items[0].Tax.Amount != 226 {
+		t.Fatalf("exclusive: subtotal=%d tax=%d total=%d", ex.SubtotalAmount.Amount, ex.TaxAmount.Amount, ex.TotalAmount.Amount)
+	}
+
+	in := newOrder()
+	if err := in.ApplyTax([]Money{eur(190)}, true); err != nil {
+		t.Fatalf("inclusive: %v", err)
+	}
+	if in.SubtotalAmount.Amount != 1000 || in.TaxAmount.Amount != 190 || in.TotalAmount.Amount != 1190 || !in.TaxInclusive {
+		t.Fatalf("inclusive: subtotal=%d tax=%d total=%d", in.SubtotalAmount.Amount, in.TaxAmount.Amount, in.TotalAmount.Amount)
+	}
+
+	if err := in.ApplyTax([]Money{eur(1), eur(2)}, true); err == nil {
+		t.Fatalf("line count mismatch should fail")
+	}
+}
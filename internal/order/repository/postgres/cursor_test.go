@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cc := newCursorCodec([]byte("secret"))
+	p := ListParams{Sort: SortCreatedAt, Desc: true, Statuses: []domain.Status{domain.StatusPaid, domain.StatusCreated}}
+	o := &domain.Order{ID: uuid.New(), CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 123456000, time.UTC)}
+
+	tok := cc.encode(newCursor(p, o, true))
+	// statuses are compared as a set
+	p.Statuses = []domain.Status{domain.StatusCreated, domain.StatusPaid}
+	c, err := cc.decode(tok, p)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if c.ID != o.ID || !c.Backward {
+		t.Fatalf("decoded cursor mismatch: %+v", c)
+	}
+	key, err := c.keyArg()
+	if err != nil {
+		t.Fatalf("key: %v", err)
+	}
+	if ts, ok := key.(time.Time); !ok || !ts.Equal(o.CreatedAt) {
+		t.Fatalf("key: got %v want %v", key, o.CreatedAt)
+	}
+}
+
+func TestCursorRejectsTamperingAndForeignQueries(t *testing.T) {
+	cc := newCursorCodec([]byte("secret"))
+	p := ListParams{Sort: SortTotalAmount, Currency: "USD"}
+	tok := cc.encode(newCursor(p, &domain.Order{ID: uuid.New(), TotalAmount: domain.NewMoney(500, "USD")}, false))
+
+	payload, mac, _ := strings.Cut(tok, ".")
+	forged := newCursor(p, &domain.Order{ID: uuid.New(), TotalAmount: domain.NewMoney(1, "USD")}, false)
+	forgedPayload, _, _ := strings.Cut(cc.encode(forged), ".")
+
+	other := p
+	other.Currency = "EUR"
+	reversed := p
+	reversed.Desc = true
+
+	for name, tc := range map[string]struct {
+		codec cursorCodec
+		tok   string
+		p     ListParams
+	}{
+		"swapped payload": {cc, forgedPayload + "." + mac, p},
+		"truncated mac":   {cc, payload + "." + mac[:len(mac)-2], p},
+		"no mac":          {cc, payload, p},
+		"other secret":    {newCursorCodec([]byte("other")), tok, p},
+		"other filter":    {cc, tok, other},
+		"other sort":      {cc, tok, reversed},
+		"legacy format":   {cc, "2024-01-01T00:00:00Z|" + uuid.NewString(), p},
+	} {
+		if _, err := tc.codec.decode(tc.tok, tc.p); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: want ErrInvalidCursor, got %v", name, err)
+		}
+	}
+}
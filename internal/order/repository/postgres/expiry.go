@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/jackc/pgx/v5"
+)
+
+// LockExpiredInTx locks up to limit orders that are still created after their
+// TTL: ttlByCurrency for their currency, or ttl otherwise. A TTL of zero never
+// expires. Rows locked by another transaction are skipped, so several
+// replicas can sweep at the same time.
+func (r *Repo) LockExpiredInTx(ctx context.Context, tx pgx.Tx, ttl time.Duration, ttlByCurrency map[string]time.Duration, limit int) ([]*domain.Order, error) {
+	currencies := make([]string, 0, len(ttlByCurrency))
+	secs := make([]float64, 0, len(ttlByCurrency))
+	for c, d := range ttlByCurrency {
+		currencies = append(currencies, c)
+		secs = append(secs, d.Seconds())
+	}
+	rows, err := tx.Query(ctx, `
+		WITH ttl AS (SELECT * FROM unnest($1::text[], $2::float8[]) AS t(currency, secs))
+		SELECT `+orderColumns+`
+		FROM orders
+		WHERE status = 'created'
+		  AND COALESCE((SELECT secs FROM ttl WHERE ttl.currency = orders.currency), $3) > 0
+		  AND created_at < now() - make_interval(secs => COALESCE((SELECT secs FROM ttl WHERE ttl.currency = orders.currency), $3))
+		ORDER BY created_at
+		LIMIT $4
+		FOR UPDATE SKIP LOCKED`,
+		currencies, secs, ttl.Seconds(), limit)
+	if err != nil {
+		r.log.Error("failed to query expired orders", log.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*domain.Order
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			r.log.Error("failed to scan expired order", log.Err(err))
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if err := attachItems(ctx, tx, orders...); err != nil {
+		r.log.Error("failed to load order items", log.Err(err))
+		return nil, err
+	}
+
+	return orders, nil
+}
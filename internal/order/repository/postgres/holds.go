@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const holdColumns = `id, order_id, reason, note, placed_by, placed_at, COALESCE(released_by, ''), released_at`
+
+// AddHoldInTx stores a new hold and the status o returns to once released.
+func (r *Repo) AddHoldInTx(ctx context.Context, tx pgx.Tx, o *domain.Order, h *domain.Hold) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO order_holds (id, order_id, reason, note, placed_by, placed_at)
+		VALUES ($1,$2,$3,$4,$5,$6)`,
+		h.ID, h.OrderID, h.Reason, h.Note, h.PlacedBy, h.PlacedAt); err != nil {
+		r.log.Error("failed to insert hold", log.Err(err))
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE orders SET held_status=$2 WHERE id=$1`, o.ID, o.HeldStatus); err != nil {
+		r.log.Error("failed to update held status", log.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// ReleaseHoldsInTx marks every active hold of an order released, e.g. when
+// the order is cancelled while held.
+func (r *Repo) ReleaseHoldsInTx(ctx context.Context, tx pgx.Tx, orderID uuid.UUID, actor string, at time.Time) error {
+	if _, err := tx.Exec(ctx, `
+		UPDATE order_holds SET released_by=$2, released_at=$3
+		WHERE order_id=$1 AND released_at IS NULL`, orderID, actor, at); err != nil {
+		r.log.Error("failed to release holds", log.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// ReleaseHoldInTx marks a hold released.
+func (r *Repo) ReleaseHoldInTx(ctx context.Context, tx pgx.Tx, h *domain.Hold) error {
+	ct, err := tx.Exec(ctx, `
+		UPDATE order_holds SET released_by=$2, released_at=$3
+		WHERE id=$1 AND released_at IS NULL`, h.ID, h.ReleasedBy, h.ReleasedAt)
+	if err != nil {
+		r.log.Error("failed to release hold", log.Err(err))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return domain.ErrHoldNotFound
+	}
+
+	return nil
+}
+
+// ListHolds returns all holds of an order, released ones included, oldest
+// first.
+func (r *Repo) ListHolds(ctx context.Context, orderID uuid.UUID) ([]domain.Hold, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+holdColumns+`
+		FROM order_holds
+		WHERE order_id=$1
+		ORDER BY placed_at, id`, orderID)
+	if err != nil {
+		r.log.Error("failed to list holds", log.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := []domain.Hold{}
+	for rows.Next() {
+		h, err := scanHold(rows)
+		if err != nil {
+			r.log.Error("failed to scan hold", log.Err(err))
+			return nil, err
+		}
+		holds = append(holds, h)
+	}
+
+	return holds, rows.Err()
+}
+
+// attachHolds loads the active holds of already scanned orders.
+func attachHolds(ctx context.Context, q querier, orders ...*domain.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*domain.Order, len(orders))
+	ids := make([]uuid.UUID, len(orders))
+	for i, o := range orders {
+		byID[o.ID], ids[i] = o, o.ID
+	}
+	rows, err := q.Query(ctx, `
+		SELECT `+holdColumns+`
+		FROM order_holds
+		WHERE order_id = ANY($1) AND released_at IS NULL
+		ORDER BY order_id, placed_at, id`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		h, err := scanHold(rows)
+		if err != nil {
+			return err
+		}
+		o := byID[h.OrderID]
+		o.Holds = append(o.Holds, h)
+	}
+
+	return rows.Err()
+}
+
+func scanHold(row pgx.Row) (domain.Hold, error) {
+	var h domain.Hold
+	err := row.Scan(&h.ID, &h.OrderID, &h.Reason, &h.Note, &h.PlacedBy, &h.PlacedAt, &h.ReleasedBy, &h.ReleasedAt)
+	return h, err
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// querier is satisfied by both the pool and a transaction.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func insertItemsInTx(ctx context.Context, tx pgx.Tx, orderID uuid.UUID, items []domain.Item) error {
+	var (
+		lineIDs    = make([]uuid.UUID, len(items))
+		skus       = make([]string, len(items))
+		quantities = make([]int32, len(items))
+		prices     = make([]int64, len(items))
+		totals     = make([]int64, len(items))
+		classes    = make([]string, len(items))
+		taxes      = make([]int64, len(items))
+	)
+	for i, it := range items {
+		lineIDs[i], skus[i], quantities[i], prices[i], totals[i] = it.LineID, it.SKU, int32(it.Quantity), it.Price.Amount, it.LineTotal.Amount
+		classes[i], taxes[i] = it.TaxClass, it.Tax.Amount
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO order_items (line_id, order_id, line_no, sku, quantity, price_minor, line_total, tax_class, tax_minor)
+		SELECT l.line_id, $1, l.line_no, l.sku, l.quantity, l.price_minor, l.line_total, l.tax_class, l.tax_minor
+		FROM unnest($2::uuid[], $3::text[], $4::int[], $5::bigint[], $6::bigint[], $7::text[], $8::bigint[])
+		     WITH ORDINALITY AS l(line_id, sku, quantity, price_minor, line_total, tax_class, tax_minor, line_no)`,
+		orderID, lineIDs, skus, quantities, prices, totals, classes, taxes)
+
+	return err
+}
+
+// loadItems fetches the lines of all given orders in one query.
+func loadItems(ctx context.Context, q querier, orderIDs []uuid.UUID) (map[uuid.UUID][]domain.Item, error) {
+	rows, err := q.Query(ctx, `
+		SELECT i.order_id, o.currency, i.line_id, i.sku, i.quantity, i.price_minor, i.line_total, i.shipped_quantity, i.tax_class, i.tax_minor
+		FROM order_items i
+		JOIN orders o ON o.id = i.order_id
+		WHERE i.order_id = ANY($1)
+		ORDER BY i.order_id, i.line_no`, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make(map[uuid.UUID][]domain.Item, len(orderIDs))
+	for rows.Next() {
+		var (
+			orderID              uuid.UUID
+			currency             string
+			price, total, taxAmt int64
+			it                   domain.Item
+		)
+		if err := rows.Scan(&orderID, &currency, &it.LineID, &it.SKU, &it.Quantity, &price, &total, &it.ShippedQuantity, &it.TaxClass, &taxAmt); err != nil {
+			return nil, err
+		}
+		it.Price = domain.NewMoney(price, currency)
+		it.LineTotal = domain.NewMoney(total, currency)
+		it.Tax = domain.NewMoney(taxAmt, currency)
+		items[orderID] = append(items[orderID], it)
+	}
+
+	return items, rows.Err()
+}
+
+// attachItems loads and assigns lines for a set of already scanned orders.
+func attachItems(ctx context.Context, q querier, orders ...*domain.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	items, err := loadItems(ctx, q, ids)
+	if err != nil {
+		return err
+	}
+	for _, o := range orders {
+		o.Items = items[o.ID]
+	}
+
+	return nil
+}
+
+// ReplaceItemsInTx stores the lines and discounts of o after ChangeItems:
+// lines that are gone are deleted, new lines are numbered after the existing
+// ones and the quantity, line total and tax of every line are rewritten.
+func (r *Repo) ReplaceItemsInTx(ctx context.Context, tx pgx.Tx, o *domain.Order) error {
+	var (
+		lineIDs    = make([]uuid.UUID, len(o.Items))
+		skus       = make([]string, len(o.Items))
+		quantities = make([]int32, len(o.Items))
+		prices     = make([]int64, len(o.Items))
+		totals     = make([]int64, len(o.Items))
+		classes    = make([]string, len(o.Items))
+		taxes      = make([]int64, len(o.Items))
+	)
+	for i, it := range o.Items {
+		lineIDs[i], skus[i], quantities[i], prices[i], totals[i] = it.LineID, it.SKU, int32(it.Quantity), it.Price.Amount, it.LineTotal.Amount
+		classes[i], taxes[i] = it.TaxClass, it.Tax.Amount
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM order_items WHERE order_id=$1 AND NOT (line_id = ANY($2))`,
+		o.ID, lineIDs); err != nil {
+		r.log.Error("failed to delete order items", log.Err(err))
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO order_items (line_id, order_id, line_no, sku, quantity, price_minor, line_total, tax_class, tax_minor)
+		SELECT l.line_id, $1, m.line_no + row_number() OVER (ORDER BY l.ord), l.sku, l.quantity, l.price_minor, l.line_total, l.tax_class, l.tax_minor
+		FROM unnest($2::uuid[], $3::text[], $4::int[], $5::bigint[], $6::bigint[], $7::text[], $8::bigint[])
+		     WITH ORDINALITY AS l(line_id, sku, quantity, price_minor, line_total, tax_class, tax_minor, ord)
+		CROSS JOIN (SELECT COALESCE(max(line_no), 0) AS line_no FROM order_items WHERE order_id=$1) m
+		WHERE NOT EXISTS (SELECT 1 FROM order_items i WHERE i.line_id = l.line_id)`,
+		o.ID, lineIDs, skus, quantities, prices, totals, classes, taxes); err != nil {
+		r.log.Error("failed to insert order items", log.Err(err))
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE order_items i SET quantity = l.quantity, line_total = l.line_total, tax_minor = l.tax_minor
+		FROM unnest($2::uuid[], $3::int[], $4::bigint[], $5::bigint[]) AS l(line_id, quantity, line_total, tax_minor)
+		WHERE i.order_id = $1 AND i.line_id = l.line_id`,
+		o.ID, lineIDs, quantities, totals, taxes); err != nil {
+		r.log.Error("failed to update order items", log.Err(err))
+		return err
+	}
+	discounts := o.Discounts
+	if discounts == nil {
+		discounts = []domain.Discount{}
+	}
+	if _, err := tx.Exec(ctx, `UPDATE orders SET discounts=$2 WHERE id=$1`, o.ID, discounts); err != nil {
+		r.log.Error("failed to update order discounts", log.Err(err))
+		return err
+	}
+
+	return nil
+}
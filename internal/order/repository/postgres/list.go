@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/google/uuid"
+)
+
+// SortField is a column List can order by. Every sort is made total by id.
+type SortField string
+
+const (
+	SortCreatedAt   SortField = "created_at"
+	SortTotalAmount SortField = "total_amount"
+)
+
+// ListParams filters and orders List results. Zero values disable a filter.
+type ListParams struct {
+	Limit  int
+	Cursor string
+
+	CustomerID  uuid.UUID
+	Statuses    []domain.Status
+	Currency    string
+	CreatedFrom time.Time // inclusive
+	CreatedTo   time.Time // exclusive
+	MinTotal    *int64
+	MaxTotal    *int64
+
+	Sort SortField
+	Desc bool
+}
+
+// Page is one slice of a listing. Next and Prev are opaque cursors for the
+// adjacent pages and are empty at either end.
+type Page struct {
+	Orders []*domain.Order `json:"orders"`
+	Next   string          `json:"next"`
+	Prev   string          `json:"prev,omitempty"`
+}
+
+func (r *Repo) List(ctx context.Context, p ListParams) (*Page, error) {
+	if p.Limit <= 0 || p.Limit > 100 {
+		p.Limit = 20
+	}
+	if p.Sort == "" {
+		p.Sort = SortCreatedAt
+	}
+	if p.Sort != SortCreatedAt && p.Sort != SortTotalAmount {
+		return nil, fmt.Errorf("unsupported sort %q", p.Sort)
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if p.CustomerID != uuid.Nil {
+		where = append(where, "customer_id = "+arg(p.CustomerID))
+	}
+	if len(p.Statuses) > 0 {
+		// partially_refunded is not a status of its own but derived from
+		// the refunded amount
+		var (
+			statuses []string
+			partial  bool
+		)
+		for _, st := range p.Statuses {
+			if st == domain.StatusPartiallyRefunded {
+				partial = true
+				continue
+			}
+			statuses = append(statuses, string(st))
+		}
+		cond := "status = ANY(" + arg(statuses) + ")"
+		if partial {
+			cond = "(" + cond + " OR (refunded_amount > 0 AND refunded_amount < total_amount))"
+		}
+		where = append(where, cond)
+	}
+	if p.Currency != "" {
+		where = append(where, "currency = "+arg(p.Currency))
+	}
+	if !p.CreatedFrom.IsZero() {
+		where = append(where, "created_at >= "+arg(p.CreatedFrom))
+	}
+	if !p.CreatedTo.IsZero() {
+		where = append(where, "created_at < "+arg(p.CreatedTo))
+	}
+	if p.MinTotal != nil {
+		where = append(where, "total_amount >= "+arg(*p.MinTotal))
+	}
+	if p.MaxTotal != nil {
+		where = append(where, "total_amount <= "+arg(*p.MaxTotal))
+	}
+
+	// Backward pages are read in reverse order from the cursor and flipped
+	// afterwards, so both directions walk the same index.
+	backward := false
+	if p.Cursor != "" {
+		c, err := r.cursors.decode(p.Cursor, p)
+		if err != nil {
+			return nil, err
+		}
+		backward = c.Backward
+		key, err := c.keyArg()
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		op := ">"
+		if p.Desc != backward {
+			op = "<"
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)", p.Sort, op, arg(key), arg(c.ID)))
+	}
+	dir := "ASC"
+	if p.Desc != backward {
+		dir = "DESC"
+	}
+
+	q := `SELECT ` + orderColumns + `
+			FROM orders`
+	if len(where) > 0 {
+		q += "\n\t\t\tWHERE " + strings.Join(where, " AND ")
+	}
+	q += fmt.Sprintf("\n\t\t\tORDER BY %s %s, id %s\n\t\t\tLIMIT %s", p.Sort, dir, dir, arg(p.Limit+1))
+
+	rows, err := r.pool.Query(ctx, q, args...)
+	if err != nil {
+		r.log.Error("failed to list orders", log.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := Page{Orders: []*domain.Order{}}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			r.log.Error("failed to scan row", log.Err(err))
+			return nil, err
+		}
+		page.Orders = append(page.Orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("failed to list orders", log.Err(err))
+		return nil, err
+	}
+	rows.Close()
+
+	more := len(page.Orders) > p.Limit
+	if more {
+		page.Orders = page.Orders[:p.Limit]
+	}
+	if err := attachItems(ctx, r.pool, page.Orders...); err != nil {
+		r.log.Error("failed to load order items", log.Err(err))
+		return nil, err
+	}
+	if err := attachHolds(ctx, r.pool, page.Orders...); err != nil {
+		r.log.Error("failed to load order holds", log.Err(err))
+		return nil, err
+	}
+	if backward {
+		slices.Reverse(page.Orders)
+	}
+	if len(page.Orders) == 0 {
+		return &page, nil
+	}
+
+	first, last := page.Orders[0], page.Orders[len(page.Orders)-1]
+	if more || backward {
+		page.Next = r.cursors.encode(newCursor(p, last, false))
+	}
+	if (more && backward) || (!backward && p.Cursor != "") {
+		page.Prev = r.cursors.encode(newCursor(p, first, true))
+	}
+
+	return &page, nil
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CustomerUsage is what a customer has used of their quotas, including the
+// order being counted.
+type CustomerUsage struct {
+	OpenOrders     int
+	OrdersThisHour int
+	ValueToday     int64
+	HourStart      time.Time
+	DayStart       time.Time
+}
+
+// TakeQuotaInTx counts a new order of value (in reporting-currency minor
+// units) placed by customerID at at against the UTC hour and day it falls
+// in, and returns the resulting usage. The customer's counter row stays
+// locked until tx ends, so concurrent orders of one customer are counted one
+// after the other; rolling tx back gives the quota back.
+func (r *Repo) TakeQuotaInTx(ctx context.Context, tx pgx.Tx, customerID uuid.UUID, value int64, at time.Time) (CustomerUsage, error) {
+	u := CustomerUsage{HourStart: at.UTC().Truncate(time.Hour), DayStart: at.UTC().Truncate(24 * time.Hour)}
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO customer_quotas AS q (customer_id, hour_start, hour_orders, day_start, day_value)
+		VALUES ($1, $2, 1, $3, $4)
+		ON CONFLICT (customer_id) DO UPDATE SET
+		  hour_orders = CASE WHEN q.hour_start = EXCLUDED.hour_start THEN q.hour_orders + 1 ELSE 1 END,
+		  hour_start  = EXCLUDED.hour_start,
+		  day_value   = CASE WHEN q.day_start = EXCLUDED.day_start THEN q.day_value + EXCLUDED.day_value ELSE EXCLUDED.day_value END,
+		  day_start   = EXCLUDED.day_start
+		RETURNING hour_orders, day_value`,
+		customerID, u.HourStart, u.DayStart, value).Scan(&u.OrdersThisHour, &u.ValueToday); err != nil {
+		r.log.Error("failed to count customer quota", log.Err(err))
+		return CustomerUsage{}, err
+	}
+	// open orders are counted rather than tracked: they only ever go down
+	// outside Create, and Create holds the counter lock
+	open := make([]string, len(domain.OpenStatuses))
+	for i, st := range domain.OpenStatuses {
+		open[i] = string(st)
+	}
+	if err := tx.QueryRow(ctx, `
+		SELECT count(*) + 1 FROM orders
+		WHERE customer_id=$1 AND status = ANY($2)`,
+		customerID, open).Scan(&u.OpenOrders); err != nil {
+		r.log.Error("failed to count open orders", log.Err(err))
+		return CustomerUsage{}, err
+	}
+
+	return u, nil
+}
+
+// AddQuotaValueInTx adds delta (in reporting-currency minor units, possibly
+// negative) to the value customerID ordered on the UTC day of at, when an
+// order changes after it was counted. Only ValueToday and DayStart of the
+// returned usage are set. A decrease after the day rolled over gives nothing
+// back.
+func (r *Repo) AddQuotaValueInTx(ctx context.Context, tx pgx.Tx, customerID uuid.UUID, delta int64, at time.Time) (CustomerUsage, error) {
+	u := CustomerUsage{DayStart: at.UTC().Truncate(24 * time.Hour)}
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO customer_quotas AS q (customer_id, hour_start, hour_orders, day_start, day_value)
+		VALUES ($1, $2, 0, $2, GREATEST($3, 0))
+		ON CONFLICT (customer_id) DO UPDATE SET
+		  day_value = CASE WHEN q.day_start = EXCLUDED.day_start THEN GREATEST(q.day_value + $3, 0) ELSE EXCLUDED.day_value END,
+		  day_start = EXCLUDED.day_start
+		RETURNING day_value`,
+		customerID, u.DayStart, delta).Scan(&u.ValueToday); err != nil {
+		r.log.Error("failed to count customer quota", log.Err(err))
+		return CustomerUsage{}, err
+	}
+
+	return u, nil
+}
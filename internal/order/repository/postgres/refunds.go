@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// AddRefundInTx stores the refund and the order's new refunded amount.
+func (r *Repo) AddRefundInTx(ctx context.Context, tx pgx.Tx, o *domain.Order, rf *domain.Refund) error {
+	lineIDs := rf.LineIDs
+	if lineIDs == nil {
+		lineIDs = []uuid.UUID{}
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO refunds (id, order_id, amount_minor, line_ids, reason, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6)`,
+		rf.ID, rf.OrderID, rf.Amount.Amount, lineIDs, rf.Reason, rf.CreatedAt); err != nil {
+		r.log.Error("failed to insert refund", log.Err(err))
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE orders SET refunded_amount=$2 WHERE id=$1`, o.ID, o.RefundedAmount.Amount); err != nil {
+		r.log.Error("failed to update refunded amount", log.Err(err))
+		return err
+	}
+
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
@@ -15,23 +14,59 @@ import (
 )
 
 type Repo struct {
-	pool *pgxpool.Pool
-	log  *log.Logger
+	pool    *pgxpool.Pool
+	log     *log.Logger
+	cursors cursorCodec
 }
 
-func New(pool *pgxpool.Pool) *Repo { return &Repo{pool: pool} }
+type Option func(*Repo)
+
+// WithCursorSecret sets the HMAC key for pagination cursors. Without it New
+// picks a random per-process key, so cursors do not survive restarts and are
+// not accepted by other replicas.
+func WithCursorSecret(secret []byte) Option {
+	return func(r *Repo) { r.cursors = newCursorCodec(secret) }
+}
+
+func New(pool *pgxpool.Pool, logger *log.Logger, opts ...Option) (*Repo, error) {
+	r := &Repo{pool: pool, log: logger}
+	for _, o := range opts {
+		o(r)
+	}
+	if len(r.cursors.secret) == 0 {
+		cc, err := randomCursorCodec()
+		if err != nil {
+			return nil, err
+		}
+		r.cursors = cc
+	}
+
+	return r, nil
+}
+
+// orderColumns is the select list understood by scanOrder.
+const orderColumns = `id, customer_id, status, COALESCE(held_status, ''), currency, subtotal_amount, tax_amount, tax_inclusive, total_amount, discount_amount, discounts, refunded_amount, shipping_address, billing_address, reporting_currency, reporting_total, trim_scale(fx_rate)::text, fx_rate_at, version, created_at, updated_at`
 
 func (r *Repo) CreateInTx(ctx context.Context, tx pgx.Tx, o *domain.Order) error {
-	items, err := json.Marshal(o.Items)
-	if err != nil {
-		r.log.Error("failed to marshal items: %v", log.Err(err))
+	discounts := o.Discounts
+	if discounts == nil {
+		discounts = []domain.Discount{}
+	}
+	var repCur, repRate, repAt, repTotal any
+	if rep := o.Reporting; rep != nil {
+		repCur, repTotal, repRate, repAt = rep.Amount.Currency, rep.Amount.Amount, rep.Rate, rep.RateAt
 	}
-	_, err = tx.Exec(ctx,
-		`INSERT INTO orders (id, customer_id, status, currency, total_amount, items, created_at, updated_at)
-         VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`,
-		o.ID, o.CustomerID, o.Status, o.Currency, o.TotalAmount, items, o.CreatedAt, o.UpdatedAt)
+	_, err := tx.Exec(ctx,
+		`INSERT INTO orders (id, customer_id, status, currency, subtotal_amount, tax_amount, tax_inclusive, total_amount, discount_amount, discounts, shipping_address, billing_address, reporting_currency, reporting_total, fx_rate, fx_rate_at, version, created_at, updated_at)
+         VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15::numeric,$16,$17,$18,$19)`,
+		o.ID, o.CustomerID, o.Status, o.Currency, o.SubtotalAmount.Amount, o.TaxAmount.Amount, o.TaxInclusive, o.TotalAmount.Amount, o.DiscountAmount.Amount, discounts, o.ShippingAddress, o.BillingAddress, repCur, repTotal, repRate, repAt, o.Version, o.CreatedAt, o.UpdatedAt)
 	if err != nil {
-		r.log.Error("failed to init transaction: %v", log.Err(err))
+		r.log.Error("failed to insert order", log.Err(err))
+		return err
+	}
+	if err := insertItemsInTx(ctx, tx, o.ID, o.Items); err != nil {
+		r.log.Error("failed to insert order items", log.Err(err))
+		return err
 	}
 
 	return nil
@@ -55,99 +90,191 @@ func (r *Repo) AddOutboxInTx(ctx context.Context, tx pgx.Tx, aggregateID uuid.UU
 	return nil
 }
 
-func (r *Repo) UpdateStatusInTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, status domain.Status) error {
-	ct, err := tx.Exec(ctx, `UPDATE orders SET status=$2, updated_at=now() WHERE id=$1`, id, status)
+// UpdateStatusInTx is a compare-and-swap on the order version: the row is only
+// written when its version still equals version, and the version is bumped.
+// Leaving on_hold clears the held status.
+func (r *Repo) UpdateStatusInTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, status domain.Status, version int64) error {
+	ct, err := tx.Exec(ctx, `
+		UPDATE orders SET status=$2, version=version+1, updated_at=now(),
+		       held_status = CASE WHEN $2 = 'on_hold' THEN held_status END
+		WHERE id=$1 AND version=$3`, id, status, version)
 	if err != nil {
-		r.log.Error("failed to update status: %v", log.Err(err))
-		return nil
+		r.log.Error("failed to update status", log.Err(err))
+		return err
 	}
 	if ct.RowsAffected() == 0 {
-		r.log.Error("failed to update status: not found")
-		return errors.New("not found")
+		return domain.ErrVersionConflict
 	}
 
 	return nil
 }
 
-func (r *Repo) Get(ctx context.Context, id uuid.UUID) (*domain.Order, error) {
-	row := r.pool.QueryRow(ctx,
-		`SELECT id, customer_id, status, currency, total_amount, items, created_at, updated_at
-         FROM orders WHERE id=$1`, id)
+// UpdateAddressesInTx persists the shipping and billing address of o.
+func (r *Repo) UpdateAddressesInTx(ctx context.Context, tx pgx.Tx, o *domain.Order) error {
+	if _, err := tx.Exec(ctx, `
+		UPDATE orders SET shipping_address=$2, billing_address=$3
+		WHERE id=$1`, o.ID, o.ShippingAddress, o.BillingAddress); err != nil {
+		r.log.Error("failed to update addresses", log.Err(err))
+		return err
+	}
 
-	var o domain.Order
-	var items []byte
-	if err := row.Scan(&o.ID, &o.CustomerID, &o.Status, &o.Currency, &o.TotalAmount, &items, &o.CreatedAt, &o.UpdatedAt); err != nil {
-		r.log.Error("failed to get order: %v", log.Err(err))
-		return nil, err
+	return nil
+}
+
+// UpdateTotalsInTx persists the amounts of o and the tax of its lines. The
+// reporting total is rewritten but its rate snapshot is kept.
+func (r *Repo) UpdateTotalsInTx(ctx context.Context, tx pgx.Tx, o *domain.Order) error {
+	var repTotal any
+	if o.Reporting != nil {
+		repTotal = o.Reporting.Amount.Amount
 	}
-	if err := json.Unmarshal(items, &o.Items); err != nil {
-		r.log.Error("failed to unmarshal items: %v", log.Err(err))
-		return nil, err
+	if _, err := tx.Exec(ctx, `
+		UPDATE orders SET subtotal_amount=$2, tax_amount=$3, tax_inclusive=$4, total_amount=$5, discount_amount=$6, reporting_total=$7
+		WHERE id=$1`,
+		o.ID, o.SubtotalAmount.Amount, o.TaxAmount.Amount, o.TaxInclusive, o.TotalAmount.Amount, o.DiscountAmount.Amount, repTotal); err != nil {
+		r.log.Error("failed to update order totals", log.Err(err))
+		return err
+	}
+	lineIDs := make([]uuid.UUID, len(o.Items))
+	taxes := make([]int64, len(o.Items))
+	for i, it := range o.Items {
+		lineIDs[i], taxes[i] = it.LineID, it.Tax.Amount
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE order_items i SET tax_minor = l.tax_minor
+		FROM unnest($2::uuid[], $3::bigint[]) AS l(line_id, tax_minor)
+		WHERE i.order_id = $1 AND i.line_id = l.line_id`,
+		o.ID, lineIDs, taxes); err != nil {
+		r.log.Error("failed to update line taxes", log.Err(err))
+		return err
 	}
 
-	return &o, nil
+	return nil
 }
 
-type Page struct {
-	Orders []*domain.Order `json:"orders"`
-	Next   string          `json:"next"`
+func (r *Repo) AddStatusHistoryInTx(ctx context.Context, tx pgx.Tx, ch *domain.StatusChange) error {
+	var from any
+	if ch.From != "" {
+		from = ch.From
+	}
+	err := tx.QueryRow(ctx, `
+		INSERT INTO order_status_history (order_id, from_status, to_status, actor, reason, request_id)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		RETURNING id, created_at`,
+		ch.OrderID, from, ch.To, ch.Actor, ch.Reason, ch.RequestID).Scan(&ch.ID, &ch.CreatedAt)
+	if err != nil {
+		r.log.Error("failed to insert status history", log.Err(err))
+		return err
+	}
+
+	return nil
 }
 
-func (r *Repo) List(ctx context.Context, limit int, cursor string) (*Page, error) {
-	if limit <= 0 || limit > 100 {
-		limit = 20
-	}
-	var rows pgx.Rows
-	var err error
-
-	if cursor == "" {
-		rows, err = r.pool.Query(ctx, `
-			SELECT id, customer_id, status, currency, total_amount, items, created_at, updated_at
-			FROM orders
-			ORDER BY created_at, id
-			LIMIT $1`, limit+1)
-	} else {
-		var ts time.Time
-		var id uuid.UUID
-		if _, err := fmt.Sscanf(cursor, "%s|%s", &ts, &id); err != nil {
-			return nil, errors.New("invalid cursor")
-		}
-		rows, err = r.pool.Query(ctx, `
-			SELECT id, customer_id, status, currency, total_amount, items, created_at, updated_at
-			FROM orders
-			WHERE (created_at, id) > ($1, $2)
-			ORDER BY created_at, id
-			LIMIT $3`, ts, id, limit+1)
-	}
+// ListStatusHistory returns the status timeline of an order, oldest first.
+func (r *Repo) ListStatusHistory(ctx context.Context, orderID uuid.UUID) ([]domain.StatusChange, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, order_id, COALESCE(from_status, ''), to_status, actor, reason, request_id, created_at
+		FROM order_status_history
+		WHERE order_id=$1
+		ORDER BY id`, orderID)
 	if err != nil {
-		r.log.Error("failed to list orders: %v", log.Err(err))
+		r.log.Error("failed to list status history", log.Err(err))
 		return nil, err
 	}
 	defer rows.Close()
 
-	var page Page
+	history := []domain.StatusChange{}
 	for rows.Next() {
-		var o domain.Order
-		var items []byte
-		if err := rows.Scan(&o.ID, &o.CustomerID, &o.Status, &o.Currency, &o.TotalAmount, &items, &o.CreatedAt, &o.UpdatedAt); err != nil {
-			r.log.Error("failed to scan row: %v", log.Err(err))
+		var ch domain.StatusChange
+		if err := rows.Scan(&ch.ID, &ch.OrderID, &ch.From, &ch.To, &ch.Actor, &ch.Reason, &ch.RequestID, &ch.CreatedAt); err != nil {
+			r.log.Error("failed to scan status history", log.Err(err))
 			return nil, err
 		}
-		if err := json.Unmarshal(items, &o.Items); err != nil {
-			r.log.Error("failed to unmarshal items: %v", log.Err(err))
-			return nil, err
+		history = append(history, ch)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("failed to list status history", log.Err(err))
+		return nil, err
+	}
+
+	return history, nil
+}
+
+func (r *Repo) Get(ctx context.Context, id uuid.UUID) (*domain.Order, error) {
+	row := r.pool.QueryRow(ctx,
+		`SELECT `+orderColumns+`
+         FROM orders WHERE id=$1`, id)
+
+	o, err := scanOrder(row)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			r.log.Error("failed to get order", log.Err(err))
 		}
-		page.Orders = append(page.Orders, &o)
+		return nil, err
 	}
-	if len(page.Orders) > limit {
-		last := page.Orders[limit-1]
-		page.Orders = page.Orders[:limit]
-		page.Next = fmt.Sprintf("%s|%s", last.CreatedAt.UTC().Format(time.RFC3339Nano), last.ID)
+	if err := attachItems(ctx, r.pool, o); err != nil {
+		r.log.Error("failed to load order items", log.Err(err))
+		return nil, err
 	}
-	if rows.Err() != nil {
-		r.log.Error("failed to list orders: %v", log.Err(err))
-		return nil, rows.Err()
+	if err := attachHolds(ctx, r.pool, o); err != nil {
+		r.log.Error("failed to load order holds", log.Err(err))
+		return nil, err
 	}
 
-	return &page, rows.Err()
+	return o, nil
+}
+
+// GetForUpdateInTx loads an order and locks its row until tx ends, so the
+// caller can apply a domain transition without racing concurrent writers.
+func (r *Repo) GetForUpdateInTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*domain.Order, error) {
+	row := tx.QueryRow(ctx,
+		`SELECT `+orderColumns+`
+         FROM orders WHERE id=$1
+         FOR UPDATE`, id)
+
+	o, err := scanOrder(row)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			r.log.Error("failed to lock order", log.Err(err))
+		}
+		return nil, err
+	}
+	if err := attachItems(ctx, tx, o); err != nil {
+		r.log.Error("failed to load order items", log.Err(err))
+		return nil, err
+	}
+	if err := attachHolds(ctx, tx, o); err != nil {
+		r.log.Error("failed to load order holds", log.Err(err))
+		return nil, err
+	}
+
+	return o, nil
+}
+
+func scanOrder(row pgx.Row) (*domain.Order, error) {
+	var (
+		o                                 domain.Order
+		subtotal, tax, total, disc, refnd int64
+		repCur                            *string
+		repTotal                          *int64
+		repRate                           *string
+		repAt                             *time.Time
+	)
+	if err := row.Scan(&o.ID, &o.CustomerID, &o.Status, &o.HeldStatus, &o.Currency, &subtotal, &tax, &o.TaxInclusive, &total, &disc, &o.Discounts, &refnd, &o.ShippingAddress, &o.BillingAddress, &repCur, &repTotal, &repRate, &repAt, &o.Version, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	o.SubtotalAmount = domain.NewMoney(subtotal, o.Currency)
+	o.TaxAmount = domain.NewMoney(tax, o.Currency)
+	o.TotalAmount = domain.NewMoney(total, o.Currency)
+	o.DiscountAmount = domain.NewMoney(disc, o.Currency)
+	o.RefundedAmount = domain.NewMoney(refnd, o.Currency)
+	o.RefundStatus = domain.RefundStatusOf(o.RefundedAmount, o.TotalAmount)
+	if repCur != nil && repTotal != nil && repRate != nil && repAt != nil {
+		o.Reporting = &domain.ReportingAmount{Amount: domain.NewMoney(*repTotal, *repCur), Rate: *repRate, RateAt: *repAt}
+	}
+
+	return &o, nil
 }
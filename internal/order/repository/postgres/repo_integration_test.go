@@ -5,15 +5,21 @@ package postgres_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
 	pgrepo "github.com/GolangDeveloperAlmir/order-service/internal/order/repository/postgres"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/saga"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"go.uber.org/zap"
 )
 
 func withDB(t *testing.T, fn func(ctx context.Context, pool *pgxpool.Pool)) {
@@ -46,6 +52,29 @@ func withDB(t *testing.T, fn func(ctx context.Context, pool *pgxpool.Pool)) {
 		"../../../../migrations/001_init.sql",
 		"../../../../migrations/002_outbox_inbox_idempotency.sql",
 		"../../../../migrations/003_saga.sql",
+		"../../../../migrations/004_order_version.sql",
+		"../../../../migrations/005_order_status_history.sql",
+		"../../../../migrations/006_order_list_indexes.sql",
+		"../../../../migrations/007_order_items.sql",
+		"../../../../migrations/008_shipments.sql",
+		"../../../../migrations/009_refunds.sql",
+		"../../../../migrations/010_returns.sql",
+		"../../../../migrations/011_order_addresses.sql",
+		"../../../../migrations/012_catalog_prices.sql",
+		"../../../../migrations/013_promotions.sql",
+		"../../../../migrations/014_tax.sql",
+		"../../../../migrations/015_fx_rates.sql",
+		"../../../../migrations/016_money_discounts.sql",
+		"../../../../migrations/017_order_expiry.sql",
+		"../../../../migrations/018_order_holds.sql",
+		"../../../../migrations/019_risk_assessments.sql",
+		"../../../../migrations/020_customer_quotas.sql",
+		"../../../../migrations/021_outbox_dead_letters.sql",
+		"../../../../migrations/022_outbox_admin_audit.sql",
+		"../../../../migrations/023_outbox_notify.sql",
+		"../../../../migrations/024_outbox_aggregate_order.sql",
+		"../../../../migrations/025_refund_status.sql",
+		"../../../../migrations/026_saga_step_retry.sql",
 	}
 	for _, p := range migs {
 		b, err := os.ReadFile(p)
@@ -66,9 +95,18 @@ func withDB(t *testing.T, fn func(ctx context.Context, pool *pgxpool.Pool)) {
 	fn(ctx, pool)
 }
 
+func newRepo(t *testing.T, pool *pgxpool.Pool) *pgrepo.Repo {
+	t.Helper()
+	r, err := pgrepo.New(pool, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
 func TestRepo_Create_Get_List_Update_Outbox(t *testing.T) {
 	withDB(t, func(ctx context.Context, pool *pgxpool.Pool) {
-		r := pgrepo.New(pool)
+		r := newRepo(t, pool)
 
 		tx, err := pool.Begin(ctx)
 		if err != nil {
@@ -77,10 +115,12 @@ func TestRepo_Create_Get_List_Update_Outbox(t *testing.T) {
 		defer tx.Rollback(ctx)
 
 		cid := uuid.New()
-		o, err := domain.New(cid, "USD", []domain.Item{{SKU: "X", Quantity: 2, PriceMinor: 200}})
+		ship := &domain.Address{Name: "Jane Doe", Line1: "1 Main St", City: "Springfield", PostalCode: "12345", Country: "US"}
+		o, err := domain.New(cid, "USD", []domain.Item{{SKU: "X", Quantity: 2, Price: domain.NewMoney(200, "USD")}}, ship, nil)
 		if err != nil {
 			t.Fatal(err)
 		}
+		o.Reporting = &domain.ReportingAmount{Amount: domain.NewMoney(368, "EUR"), Rate: "0.92", RateAt: o.CreatedAt}
 		if err := r.CreateInTx(ctx, tx, o); err != nil {
 			t.Fatal(err)
 		}
@@ -95,11 +135,20 @@ func TestRepo_Create_Get_List_Update_Outbox(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
+		if got.ShippingAddress == nil || *got.ShippingAddress != *ship || got.BillingAddress != nil {
+			t.Fatalf("addresses mismatch: %+v %+v", got.ShippingAddress, got.BillingAddress)
+		}
 		if got.TotalAmount != o.TotalAmount {
 			t.Fatalf("amount mismatch")
 		}
+		if rep := got.Reporting; rep == nil || rep.Amount != domain.NewMoney(368, "EUR") || rep.Rate != "0.92" {
+			t.Fatalf("reporting mismatch: %+v", got.Reporting)
+		}
+		if len(got.Items) != 1 || got.Items[0].LineID != o.Items[0].LineID || got.Items[0].LineTotal != domain.NewMoney(400, "USD") {
+			t.Fatalf("items mismatch: %+v", got.Items)
+		}
 
-		page, err := r.List(ctx, 10, "")
+		page, err := r.List(ctx, pgrepo.ListParams{Limit: 10})
 		if err != nil || len(page.Orders) == 0 {
 			t.Fatalf("list err: %v len=%d", err, len(page.Orders))
 		}
@@ -110,12 +159,19 @@ func TestRepo_Create_Get_List_Update_Outbox(t *testing.T) {
 		}
 		defer tx2.Rollback(ctx)
 
-		if err := r.UpdateStatusInTx(ctx, tx2, o.ID, domain.StatusPaid); err != nil {
+		if err := r.UpdateStatusInTx(ctx, tx2, o.ID, domain.StatusPaid, o.Version); err != nil {
 			t.Fatal(err)
 		}
+		if err := r.UpdateStatusInTx(ctx, tx2, o.ID, domain.StatusShipped, o.Version); !errors.Is(err, domain.ErrVersionConflict) {
+			t.Fatalf("stale version: want ErrVersionConflict, got %v", err)
+		}
 		if err := r.AddOutboxInTx(ctx, tx2, o.ID, "order.paid", map[string]any{"id": o.ID}); err != nil {
 			t.Fatal(err)
 		}
+		ch := &domain.StatusChange{OrderID: o.ID, From: domain.StatusCreated, To: domain.StatusPaid, Actor: "tester"}
+		if err := r.AddStatusHistoryInTx(ctx, tx2, ch); err != nil {
+			t.Fatal(err)
+		}
 		if err := tx2.Commit(ctx); err != nil {
 			t.Fatal(err)
 		}
@@ -127,5 +183,409 @@ func TestRepo_Create_Get_List_Update_Outbox(t *testing.T) {
 		if cnt != 2 {
 			t.Fatalf("want 2 outbox rows, got %d", cnt)
 		}
+
+		history, err := r.ListStatusHistory(ctx, o.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 1 || history[0].To != domain.StatusPaid || history[0].Actor != "tester" {
+			t.Fatalf("unexpected history: %+v", history)
+		}
+	})
+}
+
+func TestRepo_List_FiltersAndPaginates(t *testing.T) {
+	withDB(t, func(ctx context.Context, pool *pgxpool.Pool) {
+		r := newRepo(t, pool)
+
+		cid := uuid.New()
+		for i, price := range []int64{300, 100, 200} {
+			o, err := domain.New(cid, "EUR", []domain.Item{{SKU: "X", Quantity: 1, Price: domain.NewMoney(price, "EUR")}}, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx, err := pool.Begin(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.CreateInTx(ctx, tx, o); err != nil {
+				t.Fatalf("create %d: %v", i, err)
+			}
+			if err := tx.Commit(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		params := pgrepo.ListParams{
+			Limit:      2,
+			CustomerID: cid,
+			Statuses:   []domain.Status{domain.StatusCreated},
+			Sort:       pgrepo.SortTotalAmount,
+			Desc:       true,
+		}
+		first, err := r.List(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(first.Orders) != 2 || first.Next == "" {
+			t.Fatalf("first page: len=%d next=%q", len(first.Orders), first.Next)
+		}
+		if first.Orders[0].TotalAmount.Amount != 300 || first.Orders[1].TotalAmount.Amount != 200 {
+			t.Fatalf("first page order: %s, %s", first.Orders[0].TotalAmount, first.Orders[1].TotalAmount)
+		}
+		for _, o := range first.Orders {
+			if len(o.Items) != 1 || o.Items[0].Price != o.TotalAmount {
+				t.Fatalf("order %s: items not loaded: %+v", o.ID, o.Items)
+			}
+		}
+
+		params.Cursor = first.Next
+		second, err := r.List(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(second.Orders) != 1 || second.Orders[0].TotalAmount.Amount != 100 || second.Next != "" || second.Prev == "" {
+			t.Fatalf("second page: %+v", second)
+		}
+
+		params.Cursor = second.Prev
+		back, err := r.List(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(back.Orders) != 2 || back.Orders[0].ID != first.Orders[0].ID || back.Orders[1].ID != first.Orders[1].ID {
+			t.Fatalf("prev page does not match first page: %+v", back)
+		}
+		if back.Prev != "" || back.Next == "" {
+			t.Fatalf("prev page cursors: next=%q prev=%q", back.Next, back.Prev)
+		}
+
+		params.Desc = false
+		params.Cursor = first.Next
+		if _, err := r.List(ctx, params); !errors.Is(err, pgrepo.ErrInvalidCursor) {
+			t.Fatalf("cursor reused with another sort: want ErrInvalidCursor, got %v", err)
+		}
+	})
+}
+
+func TestRepo_LockExpiredInTx(t *testing.T) {
+	withDB(t, func(ctx context.Context, pool *pgxpool.Pool) {
+		r := newRepo(t, pool)
+
+		var ids []uuid.UUID
+		for _, cur := range []string{"USD", "JPY"} {
+			o, err := domain.New(uuid.New(), cur, []domain.Item{{SKU: "X", Quantity: 1, Price: domain.NewMoney(100, cur)}}, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tx, err := pool.Begin(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.CreateInTx(ctx, tx, o); err != nil {
+				t.Fatal(err)
+			}
+			if err := tx.Commit(ctx); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, o.ID)
+		}
+		if _, err := pool.Exec(ctx, `UPDATE orders SET created_at = now() - interval '2 hours'`); err != nil {
+			t.Fatal(err)
+		}
+
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback(ctx)
+		// JPY orders get three hours, everything else one
+		got, err := r.LockExpiredInTx(ctx, tx, time.Hour, map[string]time.Duration{"JPY": 3 * time.Hour}, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].ID != ids[0] || len(got[0].Items) != 1 {
+			t.Fatalf("expired: %+v", got)
+		}
+
+		// a second sweep skips the rows locked by the first
+		tx2, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx2.Rollback(ctx)
+		again, err := r.LockExpiredInTx(ctx, tx2, time.Hour, nil, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(again) != 1 || again[0].ID != ids[1] {
+			t.Fatalf("concurrent sweep: %+v", again)
+		}
+	})
+}
+
+func TestRepo_Holds(t *testing.T) {
+	withDB(t, func(ctx context.Context, pool *pgxpool.Pool) {
+		r := newRepo(t, pool)
+
+		o, err := domain.New(uuid.New(), "USD", []domain.Item{{SKU: "X", Quantity: 1, Price: domain.NewMoney(100, "USD")}}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := o.PlaceHold(domain.HoldFraud, "velocity", "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.CreateInTx(ctx, tx, o); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.AddHoldInTx(ctx, tx, o, h); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.Get(ctx, o.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != domain.StatusOnHold || got.HeldStatus != domain.StatusCreated || len(got.Holds) != 1 || got.Holds[0].ID != h.ID {
+			t.Fatalf("held order: status=%s held=%s holds=%+v", got.Status, got.HeldStatus, got.Holds)
+		}
+
+		released, err := got.ReleaseHold(h.ID, "bob")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err = pool.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.ReleaseHoldInTx(ctx, tx, released); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.UpdateStatusInTx(ctx, tx, got.ID, got.Status, got.Version); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.ReleaseHoldInTx(ctx, tx, released); !errors.Is(err, domain.ErrHoldNotFound) {
+			t.Fatalf("second release: want ErrHoldNotFound, got %v", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err = r.Get(ctx, o.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != domain.StatusCreated || got.HeldStatus != "" || len(got.Holds) != 0 {
+			t.Fatalf("released order: status=%s held=%s holds=%+v", got.Status, got.HeldStatus, got.Holds)
+		}
+		all, err := r.ListHolds(ctx, o.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 1 || all[0].ReleasedBy != "bob" || all[0].ReleasedAt == nil {
+			t.Fatalf("hold history: %+v", all)
+		}
+
+		// cancelling a held order closes its holds
+		h2, err := got.PlaceHold(domain.HoldStock, "", "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err = pool.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback(ctx)
+		if err := r.AddHoldInTx(ctx, tx, got, h2); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.ReleaseHoldsInTx(ctx, tx, got.ID, "carol", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if all, err = r.ListHolds(ctx, o.ID); err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 2 || all[1].ReleasedBy != "carol" || all[1].ReleasedAt == nil {
+			t.Fatalf("holds after cancel: %+v", all)
+		}
+	})
+}
+
+func TestRepo_TakeQuotaInTx(t *testing.T) {
+	withDB(t, func(ctx context.Context, pool *pgxpool.Pool) {
+		r := newRepo(t, pool)
+		customer := uuid.New()
+		at := time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)
+
+		take := func(value int64, at time.Time, commit bool) pgrepo.CustomerUsage {
+			t.Helper()
+			tx, err := pool.Begin(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback(ctx)
+			u, err := r.TakeQuotaInTx(ctx, tx, customer, value, at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if commit {
+				if err := tx.Commit(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return u
+		}
+
+		if u := take(100, at, true); u.OrdersThisHour != 1 || u.ValueToday != 100 || u.OpenOrders != 1 {
+			t.Fatalf("first: %+v", u)
+		}
+		if u := take(50, at.Add(10*time.Minute), false); u.OrdersThisHour != 2 || u.ValueToday != 150 {
+			t.Fatalf("second: %+v", u)
+		}
+		// the rolled back order gave its quota back
+		if u := take(50, at.Add(time.Hour), true); u.OrdersThisHour != 1 || u.ValueToday != 150 {
+			t.Fatalf("next hour: %+v", u)
+		}
+		if u := take(10, at.Add(24*time.Hour), true); u.OrdersThisHour != 1 || u.ValueToday != 10 {
+			t.Fatalf("next day: %+v", u)
+		}
+
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback(ctx)
+		if u, err := r.AddQuotaValueInTx(ctx, tx, customer, 40, at.Add(24*time.Hour)); err != nil || u.ValueToday != 50 {
+			t.Fatalf("changed order: %+v (%v)", u, err)
+		}
+		if u, err := r.AddQuotaValueInTx(ctx, tx, customer, -100, at.Add(24*time.Hour)); err != nil || u.ValueToday != 0 {
+			t.Fatalf("lowered order: %+v (%v)", u, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if u := take(0, at.Add(24*time.Hour), false); u.OrdersThisHour != 2 {
+			t.Fatalf("value changes must not count orders: %+v", u)
+		}
+	})
+}
+
+func TestRepo_ReplaceItemsInTx(t *testing.T) {
+	withDB(t, func(ctx context.Context, pool *pgxpool.Pool) {
+		r := newRepo(t, pool)
+		usd := func(n int64) domain.Money { return domain.NewMoney(n, "USD") }
+
+		o, err := domain.New(uuid.New(), "USD", []domain.Item{
+			{SKU: "A", Quantity: 1, Price: usd(100)},
+			{SKU: "B", Quantity: 1, Price: usd(50)},
+		}, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.CreateInTx(ctx, tx, o); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		a := o.Items[0].LineID
+		if _, err := o.ChangeItems([]domain.LineChange{
+			{LineID: o.Items[1].LineID, Quantity: 0},
+			{LineID: a, Quantity: 4},
+			{SKU: "C", Quantity: 2, Price: usd(30)},
+		}, false); err != nil {
+			t.Fatal(err)
+		}
+		tx, err = pool.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.ReplaceItemsInTx(ctx, tx, o); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.UpdateTotalsInTx(ctx, tx, o); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.Get(ctx, o.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Items) != 2 || got.Items[0].LineID != a || got.Items[0].Quantity != 4 || got.Items[1].SKU != "C" || got.Items[1].LineTotal != usd(60) {
+			t.Fatalf("items: %+v", got.Items)
+		}
+		if got.TotalAmount != usd(460) {
+			t.Fatalf("total: %s", got.TotalAmount)
+		}
+	})
+}
+
+func TestSagaStore_PickNextPending(t *testing.T) {
+	withDB(t, func(ctx context.Context, pool *pgxpool.Pool) {
+		store := saga.NewStore(pool, zap.NewNop(), saga.WithStaleAfter(time.Minute))
+		id, err := store.Create(ctx, "test", []saga.Step{
+			{StepNo: 1, Name: "one", Action: "a1"},
+			{StepNo: 2, Name: "two", Action: "a2"},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, step, _, _, _, err := store.PickNextPending(ctx)
+		if err != nil || step != 1 {
+			t.Fatalf("first pick: step=%d err=%v", step, err)
+		}
+		// step 1 is still running, so step 2 must wait
+		if _, step, _, _, _, err := store.PickNextPending(ctx); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("picked step %d while step 1 runs (err=%v)", step, err)
+		}
+
+		// a step left started past the timeout is picked again
+		if _, err := pool.Exec(ctx, `UPDATE saga_steps SET started_at = now() - interval '2 minutes' WHERE saga_id=$1 AND step_no=1`, id); err != nil {
+			t.Fatal(err)
+		}
+		if _, step, _, _, _, err := store.PickNextPending(ctx); err != nil || step != 1 {
+			t.Fatalf("stale pick: step=%d err=%v", step, err)
+		}
+
+		if err := store.MarkStep(ctx, id, 1, "done", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, step, _, _, _, err := store.PickNextPending(ctx); err != nil || step != 2 {
+			t.Fatalf("after step 1: step=%d err=%v", step, err)
+		}
+
+		// a deferred step waits for its retry time
+		if err := store.RetryStep(ctx, id, 2, "order is on hold", time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		if _, step, _, _, _, err := store.PickNextPending(ctx); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("picked deferred step %d (err=%v)", step, err)
+		}
+		if err := store.RetryStep(ctx, id, 2, "order is on hold", time.Now().Add(-time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if _, step, _, _, _, err := store.PickNextPending(ctx); err != nil || step != 2 {
+			t.Fatalf("retry: step=%d err=%v", step, err)
+		}
 	})
 }
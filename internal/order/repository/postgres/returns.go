@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *Repo) AddReturnInTx(ctx context.Context, tx pgx.Tx, rt *domain.Return) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO order_returns (id, order_id, status, reason, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6)`,
+		rt.ID, rt.OrderID, rt.Status, rt.Reason, rt.CreatedAt, rt.UpdatedAt); err != nil {
+		r.log.Error("failed to insert return", log.Err(err))
+		return err
+	}
+
+	// a line may appear more than once in the request; store it once
+	lineIDs := make([]uuid.UUID, 0, len(rt.Lines))
+	quantities := make([]int32, 0, len(rt.Lines))
+	pos := make(map[uuid.UUID]int, len(rt.Lines))
+	for _, l := range rt.Lines {
+		if i, ok := pos[l.LineID]; ok {
+			quantities[i] += int32(l.Quantity)
+			continue
+		}
+		pos[l.LineID] = len(lineIDs)
+		lineIDs = append(lineIDs, l.LineID)
+		quantities = append(quantities, int32(l.Quantity))
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO order_return_lines (return_id, line_id, quantity)
+		SELECT $1, l.line_id, l.quantity
+		FROM unnest($2::uuid[], $3::int[]) AS l(line_id, quantity)`,
+		rt.ID, lineIDs, quantities); err != nil {
+		r.log.Error("failed to insert return lines", log.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// UpdateReturnInTx persists the status and refund of a return.
+func (r *Repo) UpdateReturnInTx(ctx context.Context, tx pgx.Tx, rt *domain.Return) error {
+	ct, err := tx.Exec(ctx, `
+		UPDATE order_returns SET status=$2, refund_id=$3, updated_at=$4
+		WHERE id=$1`, rt.ID, rt.Status, rt.RefundID, rt.UpdatedAt)
+	if err != nil {
+		r.log.Error("failed to update return", log.Err(err))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return domain.ErrReturnNotFound
+	}
+
+	return nil
+}
+
+// ListReturnsInTx returns the returns of an order, oldest first. The caller is
+// expected to hold the order row lock.
+func (r *Repo) ListReturnsInTx(ctx context.Context, tx pgx.Tx, orderID uuid.UUID) ([]*domain.Return, error) {
+	return r.listReturns(ctx, tx, orderID)
+}
+
+// ListReturns returns the returns of an order, oldest first.
+func (r *Repo) ListReturns(ctx context.Context, orderID uuid.UUID) ([]*domain.Return, error) {
+	return r.listReturns(ctx, r.pool, orderID)
+}
+
+func (r *Repo) listReturns(ctx context.Context, q querier, orderID uuid.UUID) ([]*domain.Return, error) {
+	rows, err := q.Query(ctx, `
+		SELECT rt.id, rt.order_id, rt.status, rt.reason, rt.refund_id, rt.created_at, rt.updated_at,
+		       json_agg(json_build_object('line_id', l.line_id, 'quantity', l.quantity) ORDER BY l.line_id)
+		FROM order_returns rt
+		JOIN order_return_lines l ON l.return_id = rt.id
+		WHERE rt.order_id=$1
+		GROUP BY rt.id
+		ORDER BY rt.created_at, rt.id`, orderID)
+	if err != nil {
+		r.log.Error("failed to list returns", log.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	returns := []*domain.Return{}
+	for rows.Next() {
+		var rt domain.Return
+		if err := rows.Scan(&rt.ID, &rt.OrderID, &rt.Status, &rt.Reason, &rt.RefundID, &rt.CreatedAt, &rt.UpdatedAt, &rt.Lines); err != nil {
+			r.log.Error("failed to scan return", log.Err(err))
+			return nil, err
+		}
+		returns = append(returns, &rt)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("failed to list returns", log.Err(err))
+		return nil, err
+	}
+
+	return returns, nil
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *Repo) AddShipmentInTx(ctx context.Context, tx pgx.Tx, s *domain.Shipment) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO shipments (id, order_id, carrier, tracking_number, created_at)
+		VALUES ($1,$2,$3,$4,$5)`,
+		s.ID, s.OrderID, s.Carrier, s.TrackingNumber, s.CreatedAt); err != nil {
+		r.log.Error("failed to insert shipment", log.Err(err))
+		return err
+	}
+
+	// a line may appear more than once in the request; store it once
+	lineIDs := make([]uuid.UUID, 0, len(s.Lines))
+	quantities := make([]int32, 0, len(s.Lines))
+	pos := make(map[uuid.UUID]int, len(s.Lines))
+	for _, l := range s.Lines {
+		if i, ok := pos[l.LineID]; ok {
+			quantities[i] += int32(l.Quantity)
+			continue
+		}
+		pos[l.LineID] = len(lineIDs)
+		lineIDs = append(lineIDs, l.LineID)
+		quantities = append(quantities, int32(l.Quantity))
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO shipment_lines (shipment_id, line_id, quantity)
+		SELECT $1, l.line_id, l.quantity
+		FROM unnest($2::uuid[], $3::int[]) AS l(line_id, quantity)`,
+		s.ID, lineIDs, quantities); err != nil {
+		r.log.Error("failed to insert shipment lines", log.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// UpdateShippedInTx persists the shipped quantity of every line of o.
+func (r *Repo) UpdateShippedInTx(ctx context.Context, tx pgx.Tx, o *domain.Order) error {
+	lineIDs := make([]uuid.UUID, len(o.Items))
+	shipped := make([]int32, len(o.Items))
+	for i, it := range o.Items {
+		lineIDs[i], shipped[i] = it.LineID, int32(it.ShippedQuantity)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE order_items i SET shipped_quantity = l.shipped
+		FROM unnest($2::uuid[], $3::int[]) AS l(line_id, shipped)
+		WHERE i.order_id = $1 AND i.line_id = l.line_id`,
+		o.ID, lineIDs, shipped); err != nil {
+		r.log.Error("failed to update shipped quantities", log.Err(err))
+		return err
+	}
+
+	return nil
+}
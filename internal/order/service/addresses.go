@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/observability"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ChangeAddresses replaces the shipping and/or billing address of an order
+// that has not been paid yet. A nil address is left unchanged; version is
+// checked unless it is zero. The order is scored by the risk engine again,
+// since rules look at the countries, and a total raised by the new tax is
+// charged against the customer's daily value limit.
+func (s *Service) ChangeAddresses(ctx context.Context, id uuid.UUID, version int64, shipping, billing *domain.Address, audit Audit) (*domain.Order, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "ChangeAddresses")
+	defer span.End()
+
+	if err := s.checkPostalCodes(shipping, billing); err != nil {
+		return nil, err
+	}
+	var o *domain.Order
+	err := s.tx.InTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		if o, err = s.lockInTx(ctx, tx, id, version); err != nil {
+			return err
+		}
+		prev := o.Status
+		var beforeRep *domain.ReportingAmount
+		if o.Reporting != nil {
+			rep := *o.Reporting
+			beforeRep = &rep
+		}
+		if err := o.ChangeAddresses(shipping, billing); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateAddressesInTx(ctx, tx, o); err != nil {
+			return err
+		}
+		// the tax jurisdiction follows the addresses
+		if s.tax != nil {
+			if err := s.applyTax(ctx, o); err != nil {
+				return err
+			}
+			if err := convertReporting(o); err != nil {
+				return err
+			}
+			if err := s.repo.UpdateTotalsInTx(ctx, tx, o); err != nil {
+				return err
+			}
+		}
+		assessment, hold, err := s.assessRisk(ctx, o, true)
+		if err != nil {
+			return err
+		}
+		if err := s.takeQuotaValueInTx(ctx, tx, o, beforeRep); err != nil {
+			return err
+		}
+		if err := s.saveRiskInTx(ctx, tx, o, assessment, hold); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateStatusInTx(ctx, tx, o.ID, o.Status, o.Version); err != nil {
+			s.log.Error("failed to bump order version", log.Err(err))
+			return err
+		}
+		o.Version++
+		if o.Status != prev {
+			if err := s.recordStatusInTx(ctx, tx, o.ID, prev, o.Status, audit); err != nil {
+				return err
+			}
+		}
+		payload := map[string]any{
+			"id":               o.ID,
+			"shipping_address": o.ShippingAddress,
+			"billing_address":  o.BillingAddress,
+			"tax_amount":       o.TaxAmount,
+			"total_amount":     o.TotalAmount,
+			"actor":            audit.Actor,
+		}
+
+		return s.repo.AddOutboxInTx(ctx, tx, o.ID, "order.addresses_changed", payload)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+func (s *Service) checkPostalCodes(addrs ...*domain.Address) error {
+	for _, a := range addrs {
+		if err := s.postal.Check(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
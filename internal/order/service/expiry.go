@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/observability"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ReasonPaymentTimeout is recorded on orders cancelled for not being paid in
+// time.
+const ReasonPaymentTimeout = "payment_timeout"
+
+var ordersExpired = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "orders_expired_total",
+	Help: "number of unpaid orders cancelled after their TTL",
+})
+
+// ExpiryPolicy says how long an order may stay unpaid. ByCurrency overrides
+// TTL for single currencies; a zero TTL never expires.
+type ExpiryPolicy struct {
+	TTL        time.Duration
+	ByCurrency map[string]time.Duration
+}
+
+// Enabled reports whether any order can expire under p.
+func (p ExpiryPolicy) Enabled() bool {
+	if p.TTL > 0 {
+		return true
+	}
+	for _, d := range p.ByCurrency {
+		if d > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTTLByCurrency parses per-currency TTLs written as "JPY=1h,EUR=45m".
+func ParseTTLByCurrency(s string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		code, dur, ok := strings.Cut(part, "=")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !ok {
+			return nil, fmt.Errorf("ttl %q: want CURRENCY=DURATION", part)
+		}
+		if _, known := domain.LookupCurrency(code); !known {
+			return nil, fmt.Errorf("ttl %q: unknown currency %s", part, code)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(dur))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("ttl %q: invalid duration", part)
+		}
+		ttls[code] = d
+	}
+	return ttls, nil
+}
+
+// ExpireUnpaid cancels up to limit orders left unpaid past their TTL and
+// returns how many it cancelled. Orders locked by a concurrent sweep or
+// request are left for the next run.
+func (s *Service) ExpireUnpaid(ctx context.Context, policy ExpiryPolicy, limit int) (int, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "ExpireUnpaid")
+	defer span.End()
+
+	audit := Audit{Actor: "order-expiry", Reason: ReasonPaymentTimeout}
+	var n int
+	err := s.tx.InTx(ctx, func(tx pgx.Tx) error {
+		orders, err := s.repo.LockExpiredInTx(ctx, tx, policy.TTL, policy.ByCurrency, limit)
+		if err != nil {
+			return err
+		}
+		for _, o := range orders {
+			prev := o.Status
+			if err := o.Cancel(); err != nil {
+				return err
+			}
+			if prev == domain.StatusOnHold {
+				if err := s.repo.ReleaseHoldsInTx(ctx, tx, o.ID, audit.Actor, o.UpdatedAt); err != nil {
+					return err
+				}
+			}
+			if err := s.repo.UpdateStatusInTx(ctx, tx, o.ID, o.Status, o.Version); err != nil {
+				s.log.Error("failed to cancel expired order", log.Str("order_id", o.ID.String()), log.Err(err))
+				return err
+			}
+			o.Version++
+			if err := s.recordStatusInTx(ctx, tx, o.ID, prev, o.Status, audit); err != nil {
+				return err
+			}
+			payload := map[string]any{"id": o.ID, "status": o.Status, "reason": ReasonPaymentTimeout}
+			if err := s.repo.AddOutboxInTx(ctx, tx, o.ID, "order.cancelled", payload); err != nil {
+				return err
+			}
+		}
+		n = len(orders)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ordersExpired.Add(float64(n))
+	statusUpdated.WithLabelValues(string(domain.StatusCancelled)).Add(float64(n))
+	return n, nil
+}
+
+// Expirer periodically cancels orders that were not paid in time.
+type Expirer struct {
+	svc    *Service
+	policy ExpiryPolicy
+	ticker *time.Ticker
+	batch  int
+	log    *log.Logger
+}
+
+func NewExpirer(svc *Service, policy ExpiryPolicy, interval time.Duration, batch int, logger *log.Logger) *Expirer {
+	return &Expirer{svc: svc, policy: policy, ticker: time.NewTicker(interval), batch: max(batch, 1), log: logger}
+}
+
+func (e *Expirer) Run(ctx context.Context) error {
+	defer e.ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-e.ticker.C:
+			e.sweep(ctx)
+		}
+	}
+}
+
+// sweep expires orders batch by batch until a batch comes back short.
+func (e *Expirer) sweep(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := e.svc.ExpireUnpaid(ctx, e.policy, e.batch)
+		if err != nil {
+			e.log.Error("order expiry error", log.Err(err))
+			return
+		}
+		if n > 0 {
+			e.log.Info("cancelled unpaid orders", log.Int("count", n))
+		}
+		if n < e.batch {
+			return
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTTLByCurrency(t *testing.T) {
+	got, err := ParseTTLByCurrency(" jpy=1h, EUR = 45m ,")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if want := map[string]time.Duration{"JPY": time.Hour, "EUR": 45 * time.Minute}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	for _, bad := range []string{"JPY", "XYZ=1h", "USD=soon", "USD=-1m"} {
+		if _, err := ParseTTLByCurrency(bad); err == nil {
+			t.Fatalf("%q: want error", bad)
+		}
+	}
+}
+
+func TestExpiryPolicyEnabled(t *testing.T) {
+	cases := []struct {
+		p    ExpiryPolicy
+		want bool
+	}{
+		{ExpiryPolicy{}, false},
+		{ExpiryPolicy{TTL: time.Hour}, true},
+		{ExpiryPolicy{ByCurrency: map[string]time.Duration{"USD": 0}}, false},
+		{ExpiryPolicy{ByCurrency: map[string]time.Duration{"USD": time.Minute}}, true},
+	}
+	for _, c := range cases {
+		if got := c.p.Enabled(); got != c.want {
+			t.Fatalf("%+v: got %v want %v", c.p, got, c.want)
+		}
+	}
+}
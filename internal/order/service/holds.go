@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/observability"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// PlaceHold freezes an order for manual review; it cannot be paid or shipped
+// until every hold is released. version is checked unless it is zero.
+func (s *Service) PlaceHold(ctx context.Context, id uuid.UUID, version int64, reason domain.HoldReason, note string, audit Audit) (*domain.Order, *domain.Hold, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "PlaceHold")
+	defer span.End()
+
+	return s.changeHoldInTx(ctx, id, version, audit, "order.hold_placed", func(tx pgx.Tx, o *domain.Order) (*domain.Hold, error) {
+		h, err := o.PlaceHold(reason, note, audit.Actor)
+		if err != nil {
+			return nil, err
+		}
+		return h, s.repo.AddHoldInTx(ctx, tx, o, h)
+	})
+}
+
+// ReleaseHold releases an active hold. The order returns to the status it was
+// held in once no hold is left.
+func (s *Service) ReleaseHold(ctx context.Context, id, holdID uuid.UUID, version int64, audit Audit) (*domain.Order, *domain.Hold, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "ReleaseHold")
+	defer span.End()
+
+	return s.changeHoldInTx(ctx, id, version, audit, "order.hold_released", func(tx pgx.Tx, o *domain.Order) (*domain.Hold, error) {
+		h, err := o.ReleaseHold(holdID, audit.Actor)
+		if err != nil {
+			return nil, err
+		}
+		return h, s.repo.ReleaseHoldInTx(ctx, tx, h)
+	})
+}
+
+// Holds returns every hold an order has had, released ones included.
+func (s *Service) Holds(ctx context.Context, id uuid.UUID) ([]domain.Hold, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "Holds")
+	defer span.End()
+
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListHolds(ctx, id)
+}
+
+// changeHoldInTx locks the order, applies change, bumps the version, records
+// a status change if there was one and publishes event.
+func (s *Service) changeHoldInTx(ctx context.Context, id uuid.UUID, version int64, audit Audit, event string, change func(pgx.Tx, *domain.Order) (*domain.Hold, error)) (*domain.Order, *domain.Hold, error) {
+	var (
+		o    *domain.Order
+		h    *domain.Hold
+		prev domain.Status
+	)
+	err := s.tx.InTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		if o, err = s.lockInTx(ctx, tx, id, version); err != nil {
+			return err
+		}
+		prev = o.Status
+		if h, err = change(tx, o); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateStatusInTx(ctx, tx, o.ID, o.Status, o.Version); err != nil {
+			s.log.Error("failed to update order status", log.Err(err))
+			return err
+		}
+		o.Version++
+		if o.Status != prev {
+			if err := s.recordStatusInTx(ctx, tx, o.ID, prev, o.Status, audit); err != nil {
+				return err
+			}
+		}
+		payload := map[string]any{"id": o.ID, "status": o.Status, "hold": h, "actor": audit.Actor}
+
+		return s.repo.AddOutboxInTx(ctx, tx, o.ID, event, payload)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if o.Status != prev {
+		statusUpdated.WithLabelValues(string(o.Status)).Inc()
+	}
+	return o, h, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/observability"
+	"github.com/GolangDeveloperAlmir/order-service/internal/risk"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ChangeItems adds, removes or changes lines of an order that has not been
+// paid; with replace, changes is the complete new line set. New lines are
+// priced from the catalog, kept lines keep their price. The promotions of
+// the order are applied again as of its creation, dropping codes that no
+// longer apply, then tax. A higher total is scored by the risk engine again
+// and charged against the customer's daily value limit. A change set that
+// changes no line and drops no code leaves the order as it was, version
+// included. Version is checked unless it is zero.
+func (s *Service) ChangeItems(ctx context.Context, id uuid.UUID, version int64, changes []domain.LineChange, replace bool, audit Audit) (*domain.Order, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "ChangeItems")
+	defer span.End()
+
+	var o *domain.Order
+	err := s.tx.InTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		if o, err = s.lockInTx(ctx, tx, id, version); err != nil {
+			return err
+		}
+		if err := s.priceNewLines(ctx, o.Currency, changes); err != nil {
+			return err
+		}
+		codes := make([]string, len(o.Discounts))
+		for i, d := range o.Discounts {
+			codes[i] = d.Code
+		}
+		prev, before := o.Status, o.TotalAmount
+		var beforeRep *domain.ReportingAmount
+		if o.Reporting != nil {
+			rep := *o.Reporting
+			beforeRep = &rep
+		}
+		unchanged := *o
+		diff, err := o.ChangeItems(changes, replace)
+		if err != nil {
+			return err
+		}
+		// promotions were redeemed when the order was created, so they are
+		// only evaluated again here, not counted
+		discounts, dropped, err := s.reapplyPromotions(ctx, codes, o.Currency, o.Items, o.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if diff.Empty() && len(dropped) == 0 {
+			*o = unchanged
+			return nil
+		}
+		if err := o.ApplyDiscounts(discounts); err != nil {
+			return err
+		}
+		if err := s.applyTax(ctx, o); err != nil {
+			return err
+		}
+		if err := convertReporting(o); err != nil {
+			return err
+		}
+		var (
+			assessment *risk.Assessment
+			hold       *domain.Hold
+		)
+		if o.TotalAmount.Amount > before.Amount {
+			if assessment, hold, err = s.assessRisk(ctx, o, true); err != nil {
+				return err
+			}
+		}
+		if err := s.takeQuotaValueInTx(ctx, tx, o, beforeRep); err != nil {
+			return err
+		}
+		if err := s.repo.ReplaceItemsInTx(ctx, tx, o); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateTotalsInTx(ctx, tx, o); err != nil {
+			return err
+		}
+		if len(codes) > 0 {
+			if err := s.updateRedemptionsInTx(ctx, tx, o); err != nil {
+				return err
+			}
+		}
+		if err := s.saveRiskInTx(ctx, tx, o, assessment, hold); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateStatusInTx(ctx, tx, o.ID, o.Status, o.Version); err != nil {
+			s.log.Error("failed to bump order version", log.Err(err))
+			return err
+		}
+		o.Version++
+		if o.Status != prev {
+			if err := s.recordStatusInTx(ctx, tx, o.ID, prev, o.Status, audit); err != nil {
+				return err
+			}
+		}
+		payload := map[string]any{
+			"id":              o.ID,
+			"added":           diff.Added,
+			"removed":         diff.Removed,
+			"changed":         diff.Changed,
+			"dropped_codes":   dropped,
+			"discount_amount": o.DiscountAmount,
+			"tax_amount":      o.TaxAmount,
+			"total_amount":    o.TotalAmount,
+			"actor":           audit.Actor,
+		}
+
+		return s.repo.AddOutboxInTx(ctx, tx, o.ID, "order.items_changed", payload)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// priceNewLines fills in the catalog price and tax class of the changes that
+// add a line.
+func (s *Service) priceNewLines(ctx context.Context, currency string, changes []domain.LineChange) error {
+	var (
+		idx   []int
+		items []domain.Item
+	)
+	for i, c := range changes {
+		if c.LineID == uuid.Nil {
+			idx = append(idx, i)
+			items = append(items, domain.Item{SKU: c.SKU, Quantity: c.Quantity})
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	priced, err := s.priceItems(ctx, currency, items)
+	if err != nil {
+		return err
+	}
+	for j, i := range idx {
+		changes[i].Price, changes[i].TaxClass = priced[j].Price, priced[j].TaxClass
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/repository/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+type CustomerUsage = postgres.CustomerUsage
+
+// Limits caps the orders of a single customer; zero disables a limit.
+type Limits struct {
+	// MaxOpenOrders caps orders that are neither fulfilled nor cancelled.
+	MaxOpenOrders int
+	// MaxOrdersPerHour caps orders placed per UTC clock hour.
+	MaxOrdersPerHour int
+	// MaxValuePerDay caps the reporting-currency total, in minor units, of
+	// orders placed per UTC day. Orders stored without a reporting amount,
+	// for lack of an fx rate, are not counted.
+	MaxValuePerDay int64
+}
+
+// Enabled reports whether any limit is set.
+func (l Limits) Enabled() bool {
+	return l.MaxOpenOrders > 0 || l.MaxOrdersPerHour > 0 || l.MaxValuePerDay > 0
+}
+
+// WithLimits enforces per-customer limits on Create.
+func WithLimits(l Limits) Option {
+	return func(s *Service) { s.limits = l }
+}
+
+const (
+	LimitOpenOrders    = "open_orders"
+	LimitOrdersPerHour = "orders_per_hour"
+	LimitValuePerDay   = "value_per_day"
+)
+
+// LimitError reports an order refused by a customer limit. RetryAfter is
+// when the limit resets, zero for limits that only go down as orders are
+// fulfilled or cancelled.
+type LimitError struct {
+	Limit      string
+	Max        int64
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case LimitOpenOrders:
+		return fmt.Sprintf("customer already has %d open orders", e.Max)
+	case LimitOrdersPerHour:
+		return fmt.Sprintf("customer may place at most %d orders per hour", e.Max)
+	default:
+		return fmt.Sprintf("customer may order at most %d per day", e.Max)
+	}
+}
+
+var limitRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "order_customer_limit_rejections_total",
+	Help: "orders refused by a per-customer limit",
+}, []string{"limit"})
+
+// takeQuotaInTx counts o against the limits of its customer; on a
+// *LimitError the caller must roll tx back.
+func (s *Service) takeQuotaInTx(ctx context.Context, tx pgx.Tx, o *domain.Order) error {
+	if !s.limits.Enabled() {
+		return nil
+	}
+	var value int64
+	if o.Reporting != nil {
+		value = o.Reporting.Amount.Amount
+	}
+	u, err := s.repo.TakeQuotaInTx(ctx, tx, o.CustomerID, value, o.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if err := s.limits.check(u, o.CreatedAt); err != nil {
+		limitRejections.WithLabelValues(err.Limit).Inc()
+		return err
+	}
+	return nil
+}
+
+// takeQuotaValueInTx charges an order that changed after it was counted with
+// the change of its reporting total since before. An increase is checked
+// against the daily value limit and charged to the current day; a decrease
+// is given back and never refused.
+func (s *Service) takeQuotaValueInTx(ctx context.Context, tx pgx.Tx, o *domain.Order, before *domain.ReportingAmount) error {
+	if s.limits.MaxValuePerDay <= 0 || o.Reporting == nil || before == nil {
+		return nil
+	}
+	delta := o.Reporting.Amount.Amount - before.Amount.Amount
+	if delta == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	u, err := s.repo.AddQuotaValueInTx(ctx, tx, o.CustomerID, delta, now)
+	if err != nil {
+		return err
+	}
+	if delta < 0 {
+		return nil
+	}
+	if err := (Limits{MaxValuePerDay: s.limits.MaxValuePerDay}).check(u, now); err != nil {
+		limitRejections.WithLabelValues(err.Limit).Inc()
+		return err
+	}
+	return nil
+}
+
+func (l Limits) check(u CustomerUsage, at time.Time) *LimitError {
+	switch {
+	case l.MaxOpenOrders > 0 && u.OpenOrders > l.MaxOpenOrders:
+		return &LimitError{Limit: LimitOpenOrders, Max: int64(l.MaxOpenOrders)}
+	case l.MaxOrdersPerHour > 0 && u.OrdersThisHour > l.MaxOrdersPerHour:
+		return &LimitError{Limit: LimitOrdersPerHour, Max: int64(l.MaxOrdersPerHour), RetryAfter: u.HourStart.Add(time.Hour).Sub(at)}
+	case l.MaxValuePerDay > 0 && u.ValueToday > l.MaxValuePerDay:
+		return &LimitError{Limit: LimitValuePerDay, Max: l.MaxValuePerDay, RetryAfter: u.DayStart.Add(24 * time.Hour).Sub(at)}
+	}
+	return nil
+}
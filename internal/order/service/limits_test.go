@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+func TestLimitsCheck(t *testing.T) {
+	at := time.Date(2026, 3, 1, 10, 45, 0, 0, time.UTC)
+	l := Limits{MaxOpenOrders: 3, MaxOrdersPerHour: 5, MaxValuePerDay: 100000}
+	usage := func(open, hour int, value int64) CustomerUsage {
+		return CustomerUsage{OpenOrders: open, OrdersThisHour: hour, ValueToday: value,
+			HourStart: at.Truncate(time.Hour), DayStart: at.Truncate(24 * time.Hour)}
+	}
+
+	if err := l.check(usage(3, 5, 100000), at); err != nil {
+		t.Fatalf("at the limits: %v", err)
+	}
+	for _, tc := range []struct {
+		u     CustomerUsage
+		limit string
+		retry time.Duration
+	}{
+		{usage(4, 1, 0), LimitOpenOrders, 0},
+		{usage(1, 6, 0), LimitOrdersPerHour, 15 * time.Minute},
+		{usage(1, 1, 100001), LimitValuePerDay, 13*time.Hour + 15*time.Minute},
+	} {
+		err := l.check(tc.u, at)
+		if err == nil || err.Limit != tc.limit || err.RetryAfter != tc.retry {
+			t.Fatalf("%+v: got %+v, want %s retry %s", tc.u, err, tc.limit, tc.retry)
+		}
+	}
+	if (Limits{}).check(usage(100, 100, 1<<40), at) != nil {
+		t.Fatalf("zero limits should not refuse")
+	}
+}
+
+// quotaRepo keeps the day value of one customer.
+type quotaRepo struct {
+	Repo
+	value int64
+}
+
+func (r *quotaRepo) AddQuotaValueInTx(_ context.Context, _ pgx.Tx, _ uuid.UUID, delta int64, at time.Time) (CustomerUsage, error) {
+	r.value = max(r.value+delta, 0)
+	return CustomerUsage{ValueToday: r.value, DayStart: at.Truncate(24 * time.Hour)}, nil
+}
+
+func TestTakeQuotaValueChargesChangedOrders(t *testing.T) {
+	repo := &quotaRepo{value: 800}
+	// open and hourly limits do not apply to changes of an order
+	s := New(repo, nil, nil, zap.NewNop(), WithLimits(Limits{MaxOpenOrders: 1, MaxOrdersPerHour: 1, MaxValuePerDay: 1000}))
+	o, err := domain.New(uuid.New(), "USD", []domain.Item{{SKU: "A", Quantity: 1, Price: domain.NewMoney(300, "USD")}}, nil, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	reporting := func(n int64) *domain.ReportingAmount {
+		return &domain.ReportingAmount{Amount: domain.NewMoney(n, "USD"), Rate: "1"}
+	}
+
+	o.Reporting = reporting(500)
+	if err := s.takeQuotaValueInTx(context.Background(), nil, o, reporting(300)); err != nil || repo.value != 1000 {
+		t.Fatalf("raise to the limit: value=%d err=%v", repo.value, err)
+	}
+	o.Reporting = reporting(600)
+	var le *LimitError
+	if err := s.takeQuotaValueInTx(context.Background(), nil, o, reporting(500)); !errors.As(err, &le) || le.Limit != LimitValuePerDay {
+		t.Fatalf("raise above the limit: want value_per_day LimitError, got %v", err)
+	}
+	repo.value = 1500
+	o.Reporting = reporting(100)
+	if err := s.takeQuotaValueInTx(context.Background(), nil, o, reporting(600)); err != nil || repo.value != 1000 {
+		t.Fatalf("a decrease is never refused: value=%d err=%v", repo.value, err)
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/catalog"
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+)
+
+// PriceCatalog resolves the unit prices orders are charged at.
+type PriceCatalog interface {
+	// Prices returns every price of the given SKUs, in all currencies.
+	// Unknown SKUs are left out.
+	Prices(ctx context.Context, skus []string) ([]catalog.Price, error)
+}
+
+// priceItems returns a copy of items with catalog prices in currency and
+// their tax class. Prices sent by the client are ignored.
+func (s *Service) priceItems(ctx context.Context, currency string, items []domain.Item) ([]domain.Item, error) {
+	skus := make([]string, len(items))
+	for i, it := range items {
+		skus[i] = it.SKU
+	}
+	prices, err := s.prices.Prices(ctx, skus)
+	if err != nil {
+		s.log.Error("failed to resolve prices", log.Err(err))
+		return nil, err
+	}
+	known := make(map[string]bool, len(prices))
+	unit := make(map[string]catalog.Price, len(prices))
+	for _, p := range prices {
+		known[p.SKU] = true
+		if p.Currency == currency {
+			unit[p.SKU] = p
+		}
+	}
+
+	priced := make([]domain.Item, len(items))
+	for i, it := range items {
+		price, ok := unit[it.SKU]
+		switch {
+		case !known[it.SKU]:
+			return nil, &domain.ValidationError{Msg: fmt.Sprintf("unknown sku %q", it.SKU)}
+		case !ok:
+			return nil, &domain.ValidationError{Msg: fmt.Sprintf("sku %q is not sold in %s", it.SKU, currency)}
+		}
+		it.Price = domain.NewMoney(price.PriceMinor, currency)
+		it.TaxClass = price.TaxClass
+		priced[i] = it
+	}
+	return priced, nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/catalog"
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestPriceItemsUsesCatalog(t *testing.T) {
+	s := New(nil, catalog.NewMemory(
+		catalog.Price{SKU: "A", Currency: "USD", PriceMinor: 250},
+		catalog.Price{SKU: "B", Currency: "EUR", PriceMinor: 90},
+	), nil, zap.NewNop())
+	ctx := context.Background()
+
+	items, err := s.priceItems(ctx, "USD", []domain.Item{{SKU: "A", Quantity: 2}})
+	if err != nil {
+		t.Fatalf("price: %v", err)
+	}
+	if items[0].Price != domain.NewMoney(250, "USD") {
+		t.Fatalf("client price was kept: %s", items[0].Price)
+	}
+
+	var ve *domain.ValidationError
+	if _, err := s.priceItems(ctx, "USD", []domain.Item{{SKU: "B", Quantity: 1}}); !errors.As(err, &ve) {
+		t.Fatalf("sku not sold in currency: want ValidationError, got %v", err)
+	}
+	if _, err := s.priceItems(ctx, "USD", []domain.Item{{SKU: "C", Quantity: 1}}); !errors.As(err, &ve) {
+		t.Fatalf("unknown sku: want ValidationError, got %v", err)
+	}
+}
+
+func TestPriceNewLinesOnlyPricesAddedLines(t *testing.T) {
+	s := New(nil, catalog.NewMemory(
+		catalog.Price{SKU: "A", Currency: "USD", PriceMinor: 250, TaxClass: "food"},
+	), nil, zap.NewNop())
+
+	kept := domain.LineChange{LineID: uuid.New(), Quantity: 3}
+	changes := []domain.LineChange{kept, {SKU: "A", Quantity: 1}}
+	if err := s.priceNewLines(context.Background(), "USD", changes); err != nil {
+		t.Fatalf("price: %v", err)
+	}
+	if changes[0] != kept {
+		t.Fatalf("existing line was repriced: %+v", changes[0])
+	}
+	if changes[1].Price != domain.NewMoney(250, "USD") || changes[1].TaxClass != "food" {
+		t.Fatalf("new line: %+v", changes[1])
+	}
+}
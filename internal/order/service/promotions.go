@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/internal/promotion"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Promotions looks up discount codes and counts their use.
+type Promotions interface {
+	// Lookup returns the active promotions with the given codes. Unknown codes
+	// are left out.
+	Lookup(ctx context.Context, codes []string) ([]promotion.Promotion, error)
+	// RedeemInTx counts one use of p; it returns promotion.ErrLimitReached
+	// when p is used up.
+	RedeemInTx(ctx context.Context, tx pgx.Tx, p promotion.Promotion, customerID, orderID uuid.UUID, amount int64) error
+	// UpdateRedemptionsInTx sets the discount recorded for each code redeemed
+	// on orderID; codes missing from amounts are set to zero.
+	UpdateRedemptionsInTx(ctx context.Context, tx pgx.Tx, orderID uuid.UUID, amounts map[string]int64) error
+}
+
+// WithPromotions enables discount codes on Create.
+func WithPromotions(p Promotions) Option {
+	return func(s *Service) { s.promos = p }
+}
+
+// resolvePromotions loads the promotions behind codes and works out their
+// discounts for an order with the given items placed at at.
+func (s *Service) resolvePromotions(ctx context.Context, codes []string, currency string, items []domain.Item, at time.Time) ([]promotion.Promotion, []domain.Discount, error) {
+	if len(codes) == 0 {
+		return nil, nil, nil
+	}
+	if s.promos == nil {
+		return nil, nil, &domain.ValidationError{Msg: "promotion codes are not accepted"}
+	}
+	normalized := make([]string, len(codes))
+	for i, c := range codes {
+		normalized[i] = strings.ToUpper(strings.TrimSpace(c))
+	}
+	codes = normalized
+	found, err := s.promos.Lookup(ctx, codes)
+	if err != nil {
+		s.log.Error("failed to look up promotions", log.Err(err))
+		return nil, nil, err
+	}
+	byCode := make(map[string]promotion.Promotion, len(found))
+	for _, p := range found {
+		byCode[p.Code] = p
+	}
+	promos := make([]promotion.Promotion, len(codes))
+	for i, c := range codes {
+		p, ok := byCode[c]
+		if !ok {
+			return nil, nil, &domain.ValidationError{Msg: fmt.Sprintf("unknown promotion code %q", c)}
+		}
+		promos[i] = p
+	}
+
+	applied, err := promotion.Apply(promos, currency, promotionLines(items), at)
+	if err != nil {
+		var re *promotion.RejectedError
+		if errors.As(err, &re) {
+			return nil, nil, &domain.ValidationError{Msg: re.Error()}
+		}
+		return nil, nil, err
+	}
+
+	return promos, toDiscounts(applied, currency), nil
+}
+
+// reapplyPromotions works out the discounts of an order's codes again after
+// its items changed. Codes that were deactivated or no longer qualify are
+// dropped instead of failing the change; they are returned in dropped.
+func (s *Service) reapplyPromotions(ctx context.Context, codes []string, currency string, items []domain.Item, at time.Time) (discounts []domain.Discount, dropped []string, err error) {
+	if len(codes) == 0 {
+		return nil, nil, nil
+	}
+	if s.promos == nil {
+		return nil, codes, nil
+	}
+	found, err := s.promos.Lookup(ctx, codes)
+	if err != nil {
+		s.log.Error("failed to look up promotions", log.Err(err))
+		return nil, nil, err
+	}
+	byCode := make(map[string]promotion.Promotion, len(found))
+	for _, p := range found {
+		byCode[p.Code] = p
+	}
+	var promos []promotion.Promotion
+	for _, c := range codes {
+		if p, ok := byCode[c]; ok {
+			promos = append(promos, p)
+		} else {
+			dropped = append(dropped, c)
+		}
+	}
+
+	lines := promotionLines(items)
+	for len(promos) > 0 {
+		applied, err := promotion.Apply(promos, currency, lines, at)
+		var re *promotion.RejectedError
+		if !errors.As(err, &re) {
+			if err != nil {
+				return nil, nil, err
+			}
+			return toDiscounts(applied, currency), dropped, nil
+		}
+		dropped = append(dropped, re.Code)
+		promos = slices.DeleteFunc(promos, func(p promotion.Promotion) bool { return p.Code == re.Code })
+	}
+
+	return nil, dropped, nil
+}
+
+func promotionLines(items []domain.Item) []promotion.Line {
+	lines := make([]promotion.Line, len(items))
+	for i, it := range items {
+		lines[i] = promotion.Line{SKU: it.SKU, Quantity: it.Quantity, PriceMinor: it.Price.Amount}
+	}
+	return lines
+}
+
+func toDiscounts(applied []promotion.Discount, currency string) []domain.Discount {
+	discounts := make([]domain.Discount, len(applied))
+	for i, d := range applied {
+		discounts[i] = domain.Discount{Code: d.Code, Kind: string(d.Kind), Amount: domain.NewMoney(d.AmountMinor, currency), FreeShipping: d.FreeShipping}
+	}
+	return discounts
+}
+
+// redeemInTx counts the use of every promotion applied to o.
+func (s *Service) redeemInTx(ctx context.Context, tx pgx.Tx, o *domain.Order, promos []promotion.Promotion) error {
+	amounts := make(map[string]int64, len(o.Discounts))
+	for _, d := range o.Discounts {
+		amounts[d.Code] = d.Amount.Amount
+	}
+	for _, p := range promos {
+		if err := s.promos.RedeemInTx(ctx, tx, p, o.CustomerID, o.ID, amounts[p.Code]); err != nil {
+			if errors.Is(err, promotion.ErrLimitReached) {
+				return &domain.ValidationError{Msg: fmt.Sprintf("promotion %s: %v", p.Code, err)}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// updateRedemptionsInTx records the discounts o now gets from the codes that
+// were redeemed on it; dropped codes keep their use but discount nothing.
+func (s *Service) updateRedemptionsInTx(ctx context.Context, tx pgx.Tx, o *domain.Order) error {
+	if s.promos == nil {
+		return nil
+	}
+	amounts := make(map[string]int64, len(o.Discounts))
+	for _, d := range o.Discounts {
+		amounts[d.Code] = d.Amount.Amount
+	}
+	return s.promos.UpdateRedemptionsInTx(ctx, tx, o.ID, amounts)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/promotion"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type fakePromotions []promotion.Promotion
+
+func (f fakePromotions) Lookup(_ context.Context, codes []string) ([]promotion.Promotion, error) {
+	var out []promotion.Promotion
+	for _, p := range f {
+		for _, c := range codes {
+			if p.Code == c {
+				out = append(out, p)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f fakePromotions) RedeemInTx(context.Context, pgx.Tx, promotion.Promotion, uuid.UUID, uuid.UUID, int64) error {
+	return nil
+}
+
+func (f fakePromotions) UpdateRedemptionsInTx(context.Context, pgx.Tx, uuid.UUID, map[string]int64) error {
+	return nil
+}
+
+func TestResolvePromotions(t *testing.T) {
+	s := New(nil, nil, nil, zap.NewNop(), WithPromotions(fakePromotions{
+		{Code: "TEN", Kind: promotion.KindPercentage, PercentOff: 10},
+	}))
+	ctx := context.Background()
+	items := []domain.Item{{SKU: "A", Quantity: 1, Price: domain.NewMoney(1000, "USD")}}
+
+	_, discounts, err := s.resolvePromotions(ctx, []string{" ten "}, "USD", items, time.Now())
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if len(discounts) != 1 || discounts[0].Code != "TEN" || discounts[0].Amount != domain.NewMoney(100, "USD") {
+		t.Fatalf("discounts: %+v", discounts)
+	}
+
+	var ve *domain.ValidationError
+	if _, _, err := s.resolvePromotions(ctx, []string{"NOPE"}, "USD", items, time.Now()); !errors.As(err, &ve) {
+		t.Fatalf("unknown code: want ValidationError, got %v", err)
+	}
+	if _, _, err := New(nil, nil, nil, zap.NewNop()).resolvePromotions(ctx, []string{"TEN"}, "USD", items, time.Now()); !errors.As(err, &ve) {
+		t.Fatalf("codes without promotions: want ValidationError, got %v", err)
+	}
+}
+
+func TestReapplyPromotionsDropsCodesThatNoLongerApply(t *testing.T) {
+	s := New(nil, nil, nil, zap.NewNop(), WithPromotions(fakePromotions{
+		{Code: "TEN", Kind: promotion.KindPercentage, PercentOff: 10, Stackable: true},
+		{Code: "BOGO", Kind: promotion.KindBuyXGetY, SKU: "B", BuyQuantity: 1, GetQuantity: 1, Stackable: true},
+	}))
+	// B was removed, so BOGO has nothing to discount; GONE was deactivated
+	items := []domain.Item{{SKU: "A", Quantity: 1, Price: domain.NewMoney(1000, "USD")}}
+
+	discounts, dropped, err := s.reapplyPromotions(context.Background(), []string{"BOGO", "GONE", "TEN"}, "USD", items, time.Now())
+	if err != nil {
+		t.Fatalf("reapply: %v", err)
+	}
+	if len(discounts) != 1 || discounts[0].Code != "TEN" || discounts[0].Amount != domain.NewMoney(100, "USD") {
+		t.Fatalf("discounts: %+v", discounts)
+	}
+	if len(dropped) != 2 || dropped[0] != "GONE" || dropped[1] != "BOGO" {
+		t.Fatalf("dropped: %v", dropped)
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/observability"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateRefund pays amount minor units back on a paid order. version is
+// checked unless it is zero.
+func (s *Service) CreateRefund(ctx context.Context, id uuid.UUID, version int64, amount int64, lineIDs []uuid.UUID, reason string, audit Audit) (*domain.Order, *domain.Refund, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "CreateRefund")
+	defer span.End()
+
+	var (
+		o    *domain.Order
+		rf   *domain.Refund
+		prev domain.Status
+	)
+	err := s.tx.InTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		if o, err = s.lockInTx(ctx, tx, id, version); err != nil {
+			return err
+		}
+		prev = o.Status
+		rf, err = s.refundInTx(ctx, tx, o, domain.NewMoney(amount, o.Currency), lineIDs, reason, audit)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if o.Status != prev {
+		statusUpdated.WithLabelValues(string(o.Status)).Inc()
+	}
+	return o, rf, nil
+}
+
+// refundInTx applies and persists a refund on an order locked by tx.
+func (s *Service) refundInTx(ctx context.Context, tx pgx.Tx, o *domain.Order, amount domain.Money, lineIDs []uuid.UUID, reason string, audit Audit) (*domain.Refund, error) {
+	prev := o.Status
+	rf, err := o.Refund(amount, lineIDs, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddRefundInTx(ctx, tx, o, rf); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateStatusInTx(ctx, tx, o.ID, o.Status, o.Version); err != nil {
+		s.log.Error("failed to update order status", log.Err(err))
+		return nil, err
+	}
+	o.Version++
+	if o.Status != prev {
+		if err := s.recordStatusInTx(ctx, tx, o.ID, prev, o.Status, audit); err != nil {
+			return nil, err
+		}
+	}
+	payload := map[string]any{
+		"id":              o.ID,
+		"status":          o.Status,
+		"refund_status":   o.RefundStatus,
+		"refund":          rf,
+		"refunded_amount": o.RefundedAmount,
+		"currency":        o.Currency,
+	}
+	if err := s.repo.AddOutboxInTx(ctx, tx, o.ID, "order.refunded", payload); err != nil {
+		return nil, err
+	}
+
+	return rf, nil
+}
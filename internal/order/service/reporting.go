@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/fx"
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// RateSource looks up exchange rates in effect at a point in time.
+type RateSource interface {
+	Rate(ctx context.Context, base, quote string, at time.Time) (fx.Rate, error)
+}
+
+// WithReporting stores the total of every new order converted to currency,
+// at the rate in effect when the order is created.
+func WithReporting(currency string, rates RateSource) Option {
+	return func(s *Service) { s.reporting, s.rates = currency, rates }
+}
+
+var reportingRatesMissing = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "order_reporting_rate_missing_total",
+	Help: "orders stored without a reporting amount for lack of an fx rate",
+}, []string{"currency"})
+
+// snapshotReporting converts the total of a new order to the reporting
+// currency and keeps the rate used, so later changes to the totals are
+// converted at the same rate. Without a rate the order is left without a
+// reporting amount rather than refused.
+func (s *Service) snapshotReporting(ctx context.Context, o *domain.Order) error {
+	if s.rates == nil || s.reporting == "" {
+		return nil
+	}
+	rate := fx.Rate{Base: o.Currency, Quote: s.reporting, Value: big.NewRat(1, 1), AsOf: o.CreatedAt}
+	if o.Currency != s.reporting {
+		var err error
+		if rate, err = s.rates.Rate(ctx, o.Currency, s.reporting, o.CreatedAt); err != nil {
+			if errors.Is(err, fx.ErrNoRate) {
+				reportingRatesMissing.WithLabelValues(o.Currency).Inc()
+				s.log.Warn("no fx rate for reporting amount", log.Str("order_id", o.ID.String()), log.Str("base", o.Currency), log.Str("quote", s.reporting))
+				return nil
+			}
+			s.log.Error("failed to look up fx rate", log.Err(err))
+			return err
+		}
+	}
+	o.Reporting = &domain.ReportingAmount{Amount: domain.NewMoney(0, s.reporting), Rate: formatRate(rate.Value), RateAt: rate.AsOf}
+
+	return convertReporting(o)
+}
+
+// convertReporting recomputes the reporting amount of o from its total and
+// the snapshotted rate.
+func convertReporting(o *domain.Order) error {
+	rep := o.Reporting
+	if rep == nil {
+		return nil
+	}
+	rate, ok := new(big.Rat).SetString(rep.Rate)
+	if !ok {
+		return fmt.Errorf("invalid fx rate %q", rep.Rate)
+	}
+	from, ok := domain.LookupCurrency(o.Currency)
+	if !ok {
+		return &domain.ValidationError{Msg: "unknown currency " + o.Currency}
+	}
+	to, ok := domain.LookupCurrency(rep.Amount.Currency)
+	if !ok {
+		return &domain.ValidationError{Msg: "unknown currency " + rep.Amount.Currency}
+	}
+	amount, err := fx.Convert(o.TotalAmount.Amount, from.Exponent, to.Exponent, rate)
+	if err != nil {
+		return err
+	}
+	rep.Amount.Amount = amount
+
+	return nil
+}
+
+// formatRate renders r as a plain decimal with the precision of the fx_rates
+// column.
+func formatRate(r *big.Rat) string {
+	s := strings.TrimRight(r.FloatString(12), "0")
+	return strings.TrimSuffix(s, ".")
+}
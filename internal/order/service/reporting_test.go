@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/fx"
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestSnapshotReportingConvertsAtCreationRate(t *testing.T) {
+	rates := fx.Static{
+		{Base: "USD", Quote: "JPY", Value: big.NewRat(15025, 100), AsOf: time.Now().Add(-time.Hour)},
+	}
+	s := New(nil, nil, nil, zap.NewNop(), WithReporting("USD", rates))
+
+	o, err := domain.New(uuid.New(), "JPY", []domain.Item{{SKU: "A", Quantity: 1, Price: domain.NewMoney(1503, "JPY")}}, nil, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := s.snapshotReporting(context.Background(), o); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	// 1503 JPY / 150.25 = 10.0033 USD
+	rep := o.Reporting
+	if rep == nil || rep.Amount != domain.NewMoney(1000, "USD") || rep.Rate != "0.006655574043" {
+		t.Fatalf("reporting: %+v", rep)
+	}
+
+	// later total changes reuse the snapshotted rate
+	o.TotalAmount = domain.NewMoney(3006, "JPY")
+	if err := convertReporting(o); err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	if o.Reporting.Amount.Amount != 2001 {
+		t.Fatalf("reconverted: %s", o.Reporting.Amount)
+	}
+}
+
+func TestSnapshotReportingSameCurrencyAndMissingRate(t *testing.T) {
+	s := New(nil, nil, nil, zap.NewNop(), WithReporting("USD", fx.Static{}))
+
+	o, err := domain.New(uuid.New(), "USD", []domain.Item{{SKU: "A", Quantity: 1, Price: domain.NewMoney(1234, "USD")}}, nil, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := s.snapshotReporting(context.Background(), o); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if o.Reporting.Amount.Amount != 1234 || o.Reporting.Rate != "1" {
+		t.Fatalf("reporting: %+v", o.Reporting)
+	}
+
+	o, err = domain.New(uuid.New(), "EUR", []domain.Item{{SKU: "A", Quantity: 1, Price: domain.NewMoney(100, "EUR")}}, nil, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := s.snapshotReporting(context.Background(), o); err != nil || o.Reporting != nil {
+		t.Fatalf("missing rate: want the order without a reporting amount, got %+v (%v)", o.Reporting, err)
+	}
+}
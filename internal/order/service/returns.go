@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/observability"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/saga"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Actions of the return-processing saga.
+const (
+	ActionRestockReturn = "restock_return"
+	ActionUnstockReturn = "unstock_return"
+	ActionRefundReturn  = "refund_return"
+)
+
+// ReturnSaga is the name of the saga that restocks and refunds a received
+// return.
+const ReturnSaga = "return-processing"
+
+// Sagas starts sagas as part of a transaction.
+type Sagas interface {
+	CreateInTx(ctx context.Context, tx pgx.Tx, name string, steps []saga.Step, data map[string]any) (uuid.UUID, error)
+}
+
+// WithSagas lets the service start sagas, such as the return-processing
+// saga of a received return.
+func WithSagas(sg Sagas) Option {
+	return func(s *Service) { s.sagas = sg }
+}
+
+var errNoSagas = errors.New("return processing is not configured")
+
+var returnsUpdated = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "order_return_updates_total",
+	Help: "number of return status changes",
+}, []string{"status"})
+
+// ReturnSagaSteps are the steps of the return-processing saga started once a
+// return is received: put the goods back in stock, then refund them. A failed
+// refund takes the goods out of stock again; a refund of an order on hold
+// waits until the order is released.
+func ReturnSagaSteps(orderID, returnID uuid.UUID) []saga.Step {
+	payload := map[string]any{"order_id": orderID.String(), "return_id": returnID.String()}
+	return []saga.Step{
+		{StepNo: 1, Name: "restock", Action: ActionRestockReturn, Compensate: ActionUnstockReturn, Payload: payload},
+		{StepNo: 2, Name: "refund", Action: ActionRefundReturn, Payload: payload},
+	}
+}
+
+// RegisterSagaActions makes m run the return-processing saga steps against s.
+func (s *Service) RegisterSagaActions(m *saga.Manager) {
+	m.Handle(ActionRestockReturn, returnAction(s.RestockReturn))
+	m.Handle(ActionUnstockReturn, returnAction(s.UnstockReturn))
+	m.Handle(ActionRefundReturn, returnAction(s.RefundReturn))
+}
+
+func returnAction(fn func(ctx context.Context, orderID, returnID uuid.UUID) error) saga.Executor {
+	return func(ctx context.Context, sagaID, action string, payload map[string]any) error {
+		orderID, err := uuid.Parse(fmt.Sprint(payload["order_id"]))
+		if err != nil {
+			return fmt.Errorf("%s: order_id: %w", action, err)
+		}
+		returnID, err := uuid.Parse(fmt.Sprint(payload["return_id"]))
+		if err != nil {
+			return fmt.Errorf("%s: return_id: %w", action, err)
+		}
+		return fn(ctx, orderID, returnID)
+	}
+}
+
+// Returns lists the returns of an order.
+func (s *Service) Returns(ctx context.Context, id uuid.UUID) ([]*domain.Return, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "Returns")
+	defer span.End()
+
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListReturns(ctx, id)
+}
+
+// RequestReturn opens a return for shipped lines of an order.
+func (s *Service) RequestReturn(ctx context.Context, id uuid.UUID, lines []domain.ReturnLine, reason string, audit Audit) (*domain.Return, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "RequestReturn")
+	defer span.End()
+
+	var rt *domain.Return
+	err := s.tx.InTx(ctx, func(tx pgx.Tx) error {
+		o, err := s.lockInTx(ctx, tx, id, 0)
+		if err != nil {
+			return err
+		}
+		existing, err := s.repo.ListReturnsInTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if rt, err = o.RequestReturn(existing, lines, reason); err != nil {
+			return err
+		}
+		if err := s.repo.AddReturnInTx(ctx, tx, rt); err != nil {
+			return err
+		}
+
+		return s.addReturnEventInTx(ctx, tx, rt, "order.return_requested", audit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	returnsUpdated.WithLabelValues(string(rt.Status)).Inc()
+	return rt, nil
+}
+
+func (s *Service) ApproveReturn(ctx context.Context, id, returnID uuid.UUID, audit Audit) (*domain.Return, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "ApproveReturn")
+	defer span.End()
+	return s.advanceReturn(ctx, id, returnID, (*domain.Return).Approve, "order.return_approved", audit, nil)
+}
+
+func (s *Service) RejectReturn(ctx context.Context, id, returnID uuid.UUID, audit Audit) (*domain.Return, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "RejectReturn")
+	defer span.End()
+	return s.advanceReturn(ctx, id, returnID, (*domain.Return).Reject, "order.return_rejected", audit, nil)
+}
+
+// ReceiveReturn records that the returned goods arrived and starts the
+// return-processing saga (see ReturnSagaSteps) in the same transaction, so a
+// received return is always restocked and refunded.
+func (s *Service) ReceiveReturn(ctx context.Context, id, returnID uuid.UUID, audit Audit) (*domain.Return, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "ReceiveReturn")
+	defer span.End()
+
+	if s.sagas == nil {
+		return nil, errNoSagas
+	}
+	return s.advanceReturn(ctx, id, returnID, (*domain.Return).Receive, "order.return_received", audit, func(tx pgx.Tx, rt *domain.Return) error {
+		data := map[string]any{"order_id": id.String(), "return_id": returnID.String()}
+		if _, err := s.sagas.CreateInTx(ctx, tx, ReturnSaga, ReturnSagaSteps(id, returnID), data); err != nil {
+			s.log.Error("failed to start return saga", log.Err(err))
+			return err
+		}
+		return nil
+	})
+}
+
+// advanceReturn applies a transition to a return. then, if set, runs in the
+// same transaction once the return is saved.
+func (s *Service) advanceReturn(ctx context.Context, id, returnID uuid.UUID, apply func(*domain.Return) error, eventType string, audit Audit, then func(pgx.Tx, *domain.Return) error) (*domain.Return, error) {
+	var rt *domain.Return
+	err := s.withReturnInTx(ctx, id, returnID, func(tx pgx.Tx, _ *domain.Order, r *domain.Return) error {
+		rt = r
+		if err := apply(rt); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateReturnInTx(ctx, tx, rt); err != nil {
+			return err
+		}
+		if then != nil {
+			if err := then(tx, rt); err != nil {
+				return err
+			}
+		}
+
+		return s.addReturnEventInTx(ctx, tx, rt, eventType, audit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	returnsUpdated.WithLabelValues(string(rt.Status)).Inc()
+	return rt, nil
+}
+
+// RestockReturn asks inventory to put the goods of a received return back in
+// stock.
+func (s *Service) RestockReturn(ctx context.Context, id, returnID uuid.UUID) error {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "RestockReturn")
+	defer span.End()
+	return s.returnStockEvent(ctx, id, returnID, "order.return_restocked")
+}
+
+// UnstockReturn reverses RestockReturn.
+func (s *Service) UnstockReturn(ctx context.Context, id, returnID uuid.UUID) error {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "UnstockReturn")
+	defer span.End()
+	return s.returnStockEvent(ctx, id, returnID, "order.return_restock_reverted")
+}
+
+func (s *Service) returnStockEvent(ctx context.Context, id, returnID uuid.UUID, eventType string) error {
+	return s.withReturnInTx(ctx, id, returnID, func(tx pgx.Tx, _ *domain.Order, rt *domain.Return) error {
+		if rt.Status != domain.ReturnReceived && rt.Status != domain.ReturnRefunded {
+			return &domain.ReturnTransitionError{From: rt.Status, To: domain.ReturnReceived}
+		}
+		return s.addReturnEventInTx(ctx, tx, rt, eventType, Audit{})
+	})
+}
+
+// RefundReturn refunds a received return at the order's line prices. It is a
+// no-op for a return that was already refunded, so the saga step can be
+// retried. While the order is on hold it fails with a saga.RetryLaterError,
+// so the saga tries again later instead of undoing the restock.
+func (s *Service) RefundReturn(ctx context.Context, id, returnID uuid.UUID) error {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "RefundReturn")
+	defer span.End()
+
+	var (
+		o    *domain.Order
+		prev domain.Status
+	)
+	err := s.withReturnInTx(ctx, id, returnID, func(tx pgx.Tx, locked *domain.Order, rt *domain.Return) error {
+		o, prev = locked, locked.Status
+		if rt.Status == domain.ReturnRefunded {
+			return nil
+		}
+		if rt.Status != domain.ReturnReceived {
+			return &domain.ReturnTransitionError{From: rt.Status, To: domain.ReturnRefunded}
+		}
+		if o.OnHold() {
+			return saga.RetryLater(&domain.TransitionError{From: o.Status, To: domain.StatusRefunded, Reason: "order is on hold"})
+		}
+		amount, err := o.ReturnAmount(rt)
+		if err != nil {
+			return err
+		}
+		var refundID *uuid.UUID
+		if amount.Amount > 0 {
+			audit := Audit{Reason: "return " + rt.ID.String()}
+			rf, err := s.refundInTx(ctx, tx, o, amount, rt.LineIDs(), audit.Reason, audit)
+			if err != nil {
+				return err
+			}
+			refundID = &rf.ID
+		}
+		if err := rt.MarkRefunded(refundID); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateReturnInTx(ctx, tx, rt); err != nil {
+			return err
+		}
+
+		return s.addReturnEventInTx(ctx, tx, rt, "order.return_refunded", Audit{})
+	})
+	if err != nil {
+		s.log.Error("failed to refund return", log.Str("return_id", returnID.String()), log.Err(err))
+		return err
+	}
+
+	if o.Status != prev {
+		statusUpdated.WithLabelValues(string(o.Status)).Inc()
+	}
+	return nil
+}
+
+// withReturnInTx locks the order and runs fn with one of its returns.
+func (s *Service) withReturnInTx(ctx context.Context, id, returnID uuid.UUID, fn func(tx pgx.Tx, o *domain.Order, rt *domain.Return) error) error {
+	return s.tx.InTx(ctx, func(tx pgx.Tx) error {
+		o, err := s.lockInTx(ctx, tx, id, 0)
+		if err != nil {
+			return err
+		}
+		returns, err := s.repo.ListReturnsInTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		for _, rt := range returns {
+			if rt.ID == returnID {
+				return fn(tx, o, rt)
+			}
+		}
+
+		return domain.ErrReturnNotFound
+	})
+}
+
+func (s *Service) addReturnEventInTx(ctx context.Context, tx pgx.Tx, rt *domain.Return, eventType string, audit Audit) error {
+	payload := map[string]any{
+		"id":     rt.OrderID,
+		"return": rt,
+	}
+	if audit.Actor != "" {
+		payload["actor"] = audit.Actor
+	}
+	if audit.Reason != "" {
+		payload["reason"] = audit.Reason
+	}
+
+	return s.repo.AddOutboxInTx(ctx, tx, rt.OrderID, eventType, payload)
+}
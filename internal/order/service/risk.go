@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/internal/risk"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// riskActor is recorded as the author of holds placed by the risk engine.
+const riskActor = "risk-engine"
+
+// RiskEngine scores new orders.
+type RiskEngine interface {
+	Assess(ctx context.Context, o risk.Order) (risk.Assessment, error)
+}
+
+// RiskLog keeps the assessments for auditing.
+type RiskLog interface {
+	SaveInTx(ctx context.Context, tx pgx.Tx, orderID, customerID uuid.UUID, a risk.Assessment) error
+}
+
+// WithRisk scores every order on Create; risky orders are held for review
+// or rejected.
+func WithRisk(engine RiskEngine, audit RiskLog) Option {
+	return func(s *Service) { s.risk, s.riskLog = engine, audit }
+}
+
+var riskDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "order_risk_decisions_total",
+	Help: "risk engine decisions on new orders",
+}, []string{"decision"})
+
+// assessRisk scores an order; stored tells whether it was saved before. A
+// held order is put on a fraud hold, which is returned, unless it already has
+// one; a rejected one has its assessment saved and ErrRiskRejected is
+// returned.
+func (s *Service) assessRisk(ctx context.Context, o *domain.Order, stored bool) (*risk.Assessment, *domain.Hold, error) {
+	if s.risk == nil {
+		return nil, nil, nil
+	}
+	in := risk.Order{
+		ID:         o.ID,
+		CustomerID: o.CustomerID,
+		Total:      risk.Amount{Minor: o.TotalAmount.Amount, Currency: o.TotalAmount.Currency},
+		Stored:     stored,
+	}
+	if rep := o.Reporting; rep != nil {
+		in.ReportingTotal = &risk.Amount{Minor: rep.Amount.Amount, Currency: rep.Amount.Currency}
+	}
+	if o.ShippingAddress != nil {
+		in.ShippingCountry = o.ShippingAddress.Country
+	}
+	if o.BillingAddress != nil {
+		in.BillingCountry = o.BillingAddress.Country
+	}
+	a, err := s.risk.Assess(ctx, in)
+	if err != nil {
+		s.log.Error("failed to assess order risk", log.Err(err))
+		return nil, nil, err
+	}
+	riskDecisions.WithLabelValues(string(a.Decision)).Inc()
+
+	switch a.Decision {
+	case risk.Reject:
+		s.log.Info("order rejected by risk checks", log.Str("order_id", o.ID.String()), log.Str("customer_id", o.CustomerID.String()), log.Int("score", a.Score))
+		if err := s.tx.InTx(ctx, func(tx pgx.Tx) error {
+			return s.riskLog.SaveInTx(ctx, tx, o.ID, o.CustomerID, a)
+		}); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, domain.ErrRiskRejected
+	case risk.Hold:
+		for _, h := range o.Holds {
+			if h.Reason == domain.HoldFraud {
+				return &a, nil, nil
+			}
+		}
+		h, err := o.PlaceHold(domain.HoldFraud, a.Summary(), riskActor)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &a, h, nil
+	}
+
+	return &a, nil, nil
+}
+
+// saveRiskInTx records the assessment of a stored order and announces the
+// fraud hold h placed by assessRisk, if any.
+func (s *Service) saveRiskInTx(ctx context.Context, tx pgx.Tx, o *domain.Order, a *risk.Assessment, h *domain.Hold) error {
+	if a == nil {
+		return nil
+	}
+	if err := s.riskLog.SaveInTx(ctx, tx, o.ID, o.CustomerID, *a); err != nil {
+		return err
+	}
+	if h == nil {
+		return nil
+	}
+	if err := s.repo.AddHoldInTx(ctx, tx, o, h); err != nil {
+		return err
+	}
+	payload := map[string]any{"id": o.ID, "status": o.Status, "hold": h, "actor": riskActor}
+
+	return s.repo.AddOutboxInTx(ctx, tx, o.ID, "order.hold_placed", payload)
+}
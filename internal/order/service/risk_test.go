@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/risk"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type fakeRisk struct {
+	got risk.Order
+	a   risk.Assessment
+}
+
+func (f *fakeRisk) Assess(_ context.Context, o risk.Order) (risk.Assessment, error) {
+	f.got = o
+	return f.a, nil
+}
+
+func TestAssessRiskHoldsRiskyOrders(t *testing.T) {
+	engine := &fakeRisk{a: risk.Assessment{Score: 60, Decision: risk.Hold, Factors: []risk.Factor{
+		{Rule: risk.KindCountryMismatch, Score: 60, Detail: "shipping to US, billing in DE"},
+	}}}
+	s := New(nil, nil, nil, zap.NewNop(), WithRisk(engine, nil))
+
+	ship := &domain.Address{Name: "Ann Lee", Line1: "1 Main St", City: "Springfield", PostalCode: "12345", Country: "US"}
+	bill := &domain.Address{Name: "Ann Lee", Line1: "Hauptstr. 1", City: "Berlin", PostalCode: "10115", Country: "DE"}
+	o, err := domain.New(uuid.New(), "USD", []domain.Item{{SKU: "A", Quantity: 1, Price: domain.NewMoney(1000, "USD")}}, ship, bill)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	a, h, err := s.assessRisk(context.Background(), o, false)
+	if err != nil {
+		t.Fatalf("assess: %v", err)
+	}
+	if engine.got.ShippingCountry != "US" || engine.got.BillingCountry != "DE" || engine.got.Total != (risk.Amount{Minor: 1000, Currency: "USD"}) {
+		t.Fatalf("engine input: %+v", engine.got)
+	}
+	if a == nil || a.Decision != risk.Hold || h == nil {
+		t.Fatalf("assessment: %+v", a)
+	}
+	if !o.OnHold() || o.HeldStatus != domain.StatusCreated || len(o.Holds) != 1 {
+		t.Fatalf("order: status=%s held=%s holds=%d", o.Status, o.HeldStatus, len(o.Holds))
+	}
+	if h := o.Holds[0]; h.Reason != domain.HoldFraud || h.PlacedBy != riskActor || h.Note == "" {
+		t.Fatalf("hold: %+v", h)
+	}
+
+	engine.a = risk.Assessment{Decision: risk.Accept}
+	accepted, err := domain.New(uuid.New(), "USD", []domain.Item{{SKU: "A", Quantity: 1, Price: domain.NewMoney(1000, "USD")}}, nil, nil)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if _, _, err := s.assessRisk(context.Background(), accepted, false); err != nil || accepted.OnHold() {
+		t.Fatalf("accepted order: status=%s err=%v", accepted.Status, err)
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
 	"github.com/GolangDeveloperAlmir/order-service/internal/order/repository/postgres"
@@ -17,23 +18,96 @@ import (
 
 type Repo interface {
 	CreateInTx(ctx context.Context, tx pgx.Tx, o *domain.Order) error
-	UpdateStatusInTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, status domain.Status) error
+	GetForUpdateInTx(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*domain.Order, error)
+	LockExpiredInTx(ctx context.Context, tx pgx.Tx, ttl time.Duration, ttlByCurrency map[string]time.Duration, limit int) ([]*domain.Order, error)
+	TakeQuotaInTx(ctx context.Context, tx pgx.Tx, customerID uuid.UUID, value int64, at time.Time) (CustomerUsage, error)
+	AddQuotaValueInTx(ctx context.Context, tx pgx.Tx, customerID uuid.UUID, delta int64, at time.Time) (CustomerUsage, error)
+	UpdateStatusInTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, status domain.Status, version int64) error
 	AddOutboxInTx(ctx context.Context, tx pgx.Tx, aggregateID uuid.UUID, eventType string, payload any) error
+	AddStatusHistoryInTx(ctx context.Context, tx pgx.Tx, ch *domain.StatusChange) error
+	AddShipmentInTx(ctx context.Context, tx pgx.Tx, s *domain.Shipment) error
+	UpdateShippedInTx(ctx context.Context, tx pgx.Tx, o *domain.Order) error
+	UpdateAddressesInTx(ctx context.Context, tx pgx.Tx, o *domain.Order) error
+	UpdateTotalsInTx(ctx context.Context, tx pgx.Tx, o *domain.Order) error
+	ReplaceItemsInTx(ctx context.Context, tx pgx.Tx, o *domain.Order) error
+	AddRefundInTx(ctx context.Context, tx pgx.Tx, o *domain.Order, rf *domain.Refund) error
+	AddReturnInTx(ctx context.Context, tx pgx.Tx, rt *domain.Return) error
+	UpdateReturnInTx(ctx context.Context, tx pgx.Tx, rt *domain.Return) error
+	ListReturnsInTx(ctx context.Context, tx pgx.Tx, orderID uuid.UUID) ([]*domain.Return, error)
+	AddHoldInTx(ctx context.Context, tx pgx.Tx, o *domain.Order, h *domain.Hold) error
+	ReleaseHoldInTx(ctx context.Context, tx pgx.Tx, h *domain.Hold) error
+	ReleaseHoldsInTx(ctx context.Context, tx pgx.Tx, orderID uuid.UUID, actor string, at time.Time) error
+	ListStatusHistory(ctx context.Context, orderID uuid.UUID) ([]domain.StatusChange, error)
+	ListReturns(ctx context.Context, orderID uuid.UUID) ([]*domain.Return, error)
+	ListHolds(ctx context.Context, orderID uuid.UUID) ([]domain.Hold, error)
 
 	Get(ctx context.Context, id uuid.UUID) (*domain.Order, error)
-	List(ctx context.Context, limit int, cursor string) (*Page, error)
+	List(ctx context.Context, params ListParams) (*Page, error)
 }
 
-type Page = postgres.Page
+type (
+	Page       = postgres.Page
+	ListParams = postgres.ListParams
+	SortField  = postgres.SortField
+)
+
+const (
+	SortCreatedAt   = postgres.SortCreatedAt
+	SortTotalAmount = postgres.SortTotalAmount
+)
+
+var ErrInvalidCursor = postgres.ErrInvalidCursor
+
+// Audit describes who asked for a change and why; it is recorded in the
+// order status history.
+type Audit struct {
+	Actor     string
+	Reason    string
+	RequestID string
+}
+
+// CreateCmd is the input of Create.
+type CreateCmd struct {
+	CustomerID      uuid.UUID
+	Currency        string
+	Items           []domain.Item
+	ShippingAddress *domain.Address
+	BillingAddress  *domain.Address
+	PromoCodes      []string
+}
 
 type Service struct {
-	repo Repo
-	tx   *db.TxManager
-	log  *log.Logger
+	repo   Repo
+	prices PriceCatalog
+	tx     *db.TxManager
+	log    *log.Logger
+	postal domain.PostalCodeFormats
+	promos Promotions
+	tax    TaxCalculator
+
+	reporting string
+	rates     RateSource
+
+	risk    RiskEngine
+	riskLog RiskLog
+	limits  Limits
+	sagas   Sagas
 }
 
-func New(repo Repo, tx *db.TxManager, logger *log.Logger) *Service {
-	return &Service{repo: repo, tx: tx, log: logger}
+type Option func(*Service)
+
+// WithPostalCodeFormats enforces postal code formats on order addresses.
+func WithPostalCodeFormats(f domain.PostalCodeFormats) Option {
+	return func(s *Service) { s.postal = f }
+}
+
+func New(repo Repo, prices PriceCatalog, tx *db.TxManager, logger *log.Logger, opts ...Option) *Service {
+	s := &Service{repo: repo, prices: prices, tx: tx, log: logger}
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
 }
 
 var (
@@ -47,20 +121,56 @@ var (
 	}, []string{"status"})
 )
 
-func (s *Service) Create(ctx context.Context, customerID uuid.UUID, currency string, items []domain.Item) (*domain.Order, error) {
+func (s *Service) Create(ctx context.Context, cmd CreateCmd, audit Audit) (*domain.Order, error) {
 	ctx, span := observability.Tracer("order.service").Start(ctx, "Create")
 	defer span.End()
 
-	o, err := domain.New(customerID, currency, items)
+	if err := s.checkPostalCodes(cmd.ShippingAddress, cmd.BillingAddress); err != nil {
+		return nil, err
+	}
+	items, err := s.priceItems(ctx, cmd.Currency, cmd.Items)
+	if err != nil {
+		return nil, err
+	}
+	promos, discounts, err := s.resolvePromotions(ctx, cmd.PromoCodes, cmd.Currency, items, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	o, err := domain.New(cmd.CustomerID, cmd.Currency, items, cmd.ShippingAddress, cmd.BillingAddress)
 	if err != nil {
 		s.log.Error("failed to create order", log.Err(err))
 		return nil, err
 	}
+	if err := o.ApplyDiscounts(discounts); err != nil {
+		return nil, err
+	}
+	if err := s.applyTax(ctx, o); err != nil {
+		return nil, err
+	}
+	if err := s.snapshotReporting(ctx, o); err != nil {
+		return nil, err
+	}
+	assessment, hold, err := s.assessRisk(ctx, o, false)
+	if err != nil {
+		return nil, err
+	}
 	if err := s.tx.InTx(ctx, func(tx pgx.Tx) error {
+		if err := s.takeQuotaInTx(ctx, tx, o); err != nil {
+			return err
+		}
 		if err := s.repo.CreateInTx(ctx, tx, o); err != nil {
 			s.log.Error("failed to create order", log.Err(err))
 			return err
 		}
+		if err := s.saveRiskInTx(ctx, tx, o, assessment, hold); err != nil {
+			return err
+		}
+		if err := s.redeemInTx(ctx, tx, o, promos); err != nil {
+			return err
+		}
+		if err := s.recordStatusInTx(ctx, tx, o.ID, "", o.Status, audit); err != nil {
+			return err
+		}
 		b, err := json.Marshal(o)
 		if err != nil {
 			s.log.Error("failed to marshal order", log.Err(err))
@@ -83,29 +193,105 @@ func (s *Service) Get(ctx context.Context, id uuid.UUID) (*domain.Order, error)
 	return s.repo.Get(ctx, id)
 }
 
-func (s *Service) List(ctx context.Context, limit int, cursor string) (*Page, error) {
+func (s *Service) List(ctx context.Context, params ListParams) (*Page, error) {
 	ctx, span := observability.Tracer("order.service").Start(ctx, "List")
 	defer span.End()
-	return s.repo.List(ctx, limit, cursor)
+	return s.repo.List(ctx, params)
 }
 
-func (s *Service) UpdateStatus(ctx context.Context, id uuid.UUID, status domain.Status) error {
+// UpdateStatus applies a status transition to the order if it is still at the
+// given version and returns the order as persisted.
+func (s *Service) UpdateStatus(ctx context.Context, id uuid.UUID, status domain.Status, version int64, audit Audit) (*domain.Order, error) {
 	ctx, span := observability.Tracer("order.service").Start(ctx, "UpdateStatus")
 	defer span.End()
 
+	var o *domain.Order
+	changed := false
 	err := s.tx.InTx(ctx, func(tx pgx.Tx) error {
-		if err := s.repo.UpdateStatusInTx(ctx, tx, id, status); err != nil {
+		var err error
+		if o, err = s.lockInTx(ctx, tx, id, version); err != nil {
+			return err
+		}
+		prev := o.Status
+		if err := o.Transition(status); err != nil {
+			return err
+		}
+		if o.Status == prev {
+			// no-op transition (e.g. cancelling a cancelled order)
+			return nil
+		}
+		if o.Status == domain.StatusShipped {
+			if err := s.repo.UpdateShippedInTx(ctx, tx, o); err != nil {
+				return err
+			}
+		}
+		if prev == domain.StatusOnHold && o.Status == domain.StatusCancelled {
+			if err := s.repo.ReleaseHoldsInTx(ctx, tx, id, audit.Actor, o.UpdatedAt); err != nil {
+				return err
+			}
+		}
+		if err := s.repo.UpdateStatusInTx(ctx, tx, id, o.Status, o.Version); err != nil {
 			s.log.Error("failed to update order status", log.Err(err))
 			return err
 		}
-		payload := map[string]any{"id": id, "status": status}
+		o.Version++
+		changed = true
+		if err := s.recordStatusInTx(ctx, tx, id, prev, o.Status, audit); err != nil {
+			return err
+		}
+		payload := map[string]any{"id": id, "status": o.Status}
 
-		return s.repo.AddOutboxInTx(ctx, tx, id, eventForStatus(status), payload)
+		return s.repo.AddOutboxInTx(ctx, tx, id, eventForStatus(o.Status), payload)
 	})
-	if err == nil {
+	if err != nil {
+		return nil, err
+	}
+	if changed {
 		statusUpdated.WithLabelValues(string(status)).Inc()
 	}
-	return err
+	return o, nil
+}
+
+// History returns the status timeline of an order.
+func (s *Service) History(ctx context.Context, id uuid.UUID) ([]domain.StatusChange, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "History")
+	defer span.End()
+
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListStatusHistory(ctx, id)
+}
+
+// lockInTx loads and row-locks an order for a read-modify-write. A non-zero
+// version must match the stored one.
+func (s *Service) lockInTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, version int64) (*domain.Order, error) {
+	o, err := s.repo.GetForUpdateInTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && o.Version != version {
+		return nil, domain.ErrVersionConflict
+	}
+
+	return o, nil
+}
+
+func (s *Service) recordStatusInTx(ctx context.Context, tx pgx.Tx, id uuid.UUID, from, to domain.Status, audit Audit) error {
+	ch := &domain.StatusChange{
+		OrderID:   id,
+		From:      from,
+		To:        to,
+		Actor:     audit.Actor,
+		Reason:    audit.Reason,
+		RequestID: audit.RequestID,
+	}
+	if err := s.repo.AddStatusHistoryInTx(ctx, tx, ch); err != nil {
+		s.log.Error("failed to record status history", log.Err(err))
+		return err
+	}
+
+	return nil
 }
 
 func eventForStatus(s domain.Status) string {
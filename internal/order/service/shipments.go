@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/observability"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateShipment records a (possibly partial) shipment of an order and moves
+// it to partially_shipped or shipped. version is checked unless it is zero.
+func (s *Service) CreateShipment(ctx context.Context, id uuid.UUID, version int64, lines []domain.ShipmentLine, carrier, trackingNumber string, audit Audit) (*domain.Order, *domain.Shipment, error) {
+	ctx, span := observability.Tracer("order.service").Start(ctx, "CreateShipment")
+	defer span.End()
+
+	var (
+		o    *domain.Order
+		sh   *domain.Shipment
+		prev domain.Status
+	)
+	err := s.tx.InTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		if o, err = s.lockInTx(ctx, tx, id, version); err != nil {
+			return err
+		}
+		prev = o.Status
+		if sh, err = o.Ship(lines, carrier, trackingNumber); err != nil {
+			return err
+		}
+		if err := s.repo.AddShipmentInTx(ctx, tx, sh); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateShippedInTx(ctx, tx, o); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateStatusInTx(ctx, tx, id, o.Status, o.Version); err != nil {
+			s.log.Error("failed to update order status", log.Err(err))
+			return err
+		}
+		o.Version++
+		if o.Status != prev {
+			if err := s.recordStatusInTx(ctx, tx, id, prev, o.Status, audit); err != nil {
+				return err
+			}
+		}
+		payload := map[string]any{"id": id, "status": o.Status, "shipment": sh}
+
+		return s.repo.AddOutboxInTx(ctx, tx, id, "order.shipment_created", payload)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if o.Status != prev {
+		statusUpdated.WithLabelValues(string(o.Status)).Inc()
+	}
+	return o, sh, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/internal/tax"
+)
+
+// TaxCalculator computes the tax of order lines in a jurisdiction.
+type TaxCalculator interface {
+	Inclusive() bool
+	Calculate(ctx context.Context, country, region string, lines []tax.Line) ([]int64, error)
+}
+
+// WithTax enables tax calculation. Orders are taxed where they ship to, or
+// where they are billed when there is no shipping address; orders without
+// any address are not taxed.
+func WithTax(c TaxCalculator) Option {
+	return func(s *Service) { s.tax = c }
+}
+
+// applyTax works out the tax of o after discounts.
+func (s *Service) applyTax(ctx context.Context, o *domain.Order) error {
+	if s.tax == nil {
+		return nil
+	}
+	addr := o.ShippingAddress
+	if addr == nil {
+		addr = o.BillingAddress
+	}
+	taxes := make([]domain.Money, len(o.Items))
+	for i := range taxes {
+		taxes[i] = domain.NewMoney(0, o.Currency)
+	}
+	if addr != nil {
+		net, err := o.NetLineTotals()
+		if err != nil {
+			return err
+		}
+		lines := make([]tax.Line, len(o.Items))
+		for i, it := range o.Items {
+			lines[i] = tax.Line{TaxClass: it.TaxClass, AmountMinor: net[i].Amount}
+		}
+		amounts, err := s.tax.Calculate(ctx, addr.Country, addr.Region, lines)
+		if err != nil {
+			s.log.Error("failed to calculate tax", log.Err(err))
+			return err
+		}
+		taxes = make([]domain.Money, len(amounts))
+		for i, a := range amounts {
+			taxes[i] = domain.NewMoney(a, o.Currency)
+		}
+	}
+
+	return o.ApplyTax(taxes, s.tax.Inclusive())
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/tax"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestApplyTaxUsesShippingJurisdiction(t *testing.T) {
+	calc, err := tax.NewCalculator(tax.StaticRules{
+		{Country: "DE", RateBP: 1900},
+		{Country: "DE", TaxClass: "reduced", RateBP: 700},
+	}, false, tax.RoundPerLine)
+	if err != nil {
+		t.Fatalf("calculator: %v", err)
+	}
+	s := New(nil, nil, nil, zap.NewNop(), WithTax(calc))
+	ship := &domain.Address{Name: "A", Line1: "B", City: "Berlin", PostalCode: "10115", Country: "DE"}
+	billing := &domain.Address{Name: "A", Line1: "B", City: "Paris", PostalCode: "75001", Country: "FR"}
+
+	o, err := domain.New(uuid.New(), "EUR", []domain.Item{
+		{SKU: "A", Quantity: 1, Price: domain.NewMoney(1000, "EUR"), TaxClass: "standard"},
+		{SKU: "B", Quantity: 1, Price: domain.NewMoney(1000, "EUR"), TaxClass: "reduced"},
+	}, ship, billing)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := o.ApplyDiscounts([]domain.Discount{{Code: "HALF", Amount: domain.NewMoney(1000, "EUR")}}); err != nil {
+		t.Fatalf("discount: %v", err)
+	}
+	if err := s.applyTax(context.Background(), o); err != nil {
+		t.Fatalf("tax: %v", err)
+	}
+	// each line is 500 after the discount: 95 + 35
+	if o.TaxAmount.Amount != 130 || o.SubtotalAmount.Amount != 1000 || o.TotalAmount.Amount != 1130 {
+		t.Fatalf("subtotal=%s tax=%s total=%s", o.SubtotalAmount, o.TaxAmount, o.TotalAmount)
+	}
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/request"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/respond"
+	"github.com/google/uuid"
+)
+
+type changeAddressesReq struct {
+	ShippingAddress *domain.Address `json:"shipping_address,omitempty"`
+	BillingAddress  *domain.Address `json:"billing_address,omitempty"`
+}
+
+func (h *Handler) ChangeAddresses(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chiURLParam(r, "id"))
+	if err != nil {
+		h.log.Error("failed to parse id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	version, ok := optionalIfMatch(r)
+	if !ok {
+		respond.Error(w, http.StatusBadRequest, "invalid If-Match header")
+		return
+	}
+	var req changeAddressesReq
+	if err := request.DecodeJSON(w, r, &req); err != nil || (req.ShippingAddress == nil && req.BillingAddress == nil) {
+		h.log.Error("failed to decode body: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	o, err := h.svc.ChangeAddresses(ctx, id, version, req.ShippingAddress, req.BillingAddress, auditFrom(r, ""))
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	setETag(w, o.Version)
+	respond.JSON(w, http.StatusOK, o)
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// setETag exposes the order version as a strong entity tag.
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// ifMatchVersion extracts the order version from the If-Match header. Weak
+// tags are accepted since the version is the only validator we issue.
+func ifMatchVersion(r *http.Request) (int64, bool) {
+	tag := strings.TrimSpace(r.Header.Get("If-Match"))
+	tag = strings.TrimPrefix(tag, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil || v <= 0 {
+		return 0, false
+	}
+
+	return v, true
+}
+
+// optionalIfMatch is ifMatchVersion for endpoints where the precondition is
+// optional: it yields 0 when If-Match is absent and false when it is garbled.
+func optionalIfMatch(r *http.Request) (int64, bool) {
+	if r.Header.Get("If-Match") == "" {
+		return 0, true
+	}
+	return ifMatchVersion(r)
+}
@@ -2,13 +2,13 @@ package http
 
 import (
 	"context"
+	"errors"
 	"net/http"
-	"regexp"
-	"strconv"
 	"time"
 
 	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
 	ordersvc "github.com/GolangDeveloperAlmir/order-service/internal/order/service"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/auth"
 	"github.com/GolangDeveloperAlmir/order-service/internal/platform/idempotency"
 	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
 	"github.com/GolangDeveloperAlmir/order-service/internal/platform/saga"
@@ -17,13 +17,30 @@ import (
 	"github.com/google/uuid"
 )
 
-var currencyRe = regexp.MustCompile("^[A-Z]{3}$")
+// validCurrency reports whether code is a known ISO 4217 currency.
+func validCurrency(code string) bool {
+	_, ok := domain.LookupCurrency(code)
+	return ok
+}
 
 type Service interface {
-	Create(ctx context.Context, customerID uuid.UUID, currency string, items []domain.Item) (*domain.Order, error)
+	Create(ctx context.Context, cmd ordersvc.CreateCmd, audit ordersvc.Audit) (*domain.Order, error)
 	Get(ctx context.Context, id uuid.UUID) (*domain.Order, error)
-	List(ctx context.Context, limit int, cursor string) (*ordersvc.Page, error)
-	UpdateStatus(ctx context.Context, id uuid.UUID, status domain.Status) error
+	List(ctx context.Context, params ordersvc.ListParams) (*ordersvc.Page, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status domain.Status, version int64, audit ordersvc.Audit) (*domain.Order, error)
+	History(ctx context.Context, id uuid.UUID) ([]domain.StatusChange, error)
+	CreateShipment(ctx context.Context, id uuid.UUID, version int64, lines []domain.ShipmentLine, carrier, trackingNumber string, audit ordersvc.Audit) (*domain.Order, *domain.Shipment, error)
+	ChangeAddresses(ctx context.Context, id uuid.UUID, version int64, shipping, billing *domain.Address, audit ordersvc.Audit) (*domain.Order, error)
+	CreateRefund(ctx context.Context, id uuid.UUID, version int64, amount int64, lineIDs []uuid.UUID, reason string, audit ordersvc.Audit) (*domain.Order, *domain.Refund, error)
+	Returns(ctx context.Context, id uuid.UUID) ([]*domain.Return, error)
+	RequestReturn(ctx context.Context, id uuid.UUID, lines []domain.ReturnLine, reason string, audit ordersvc.Audit) (*domain.Return, error)
+	ApproveReturn(ctx context.Context, id, returnID uuid.UUID, audit ordersvc.Audit) (*domain.Return, error)
+	RejectReturn(ctx context.Context, id, returnID uuid.UUID, audit ordersvc.Audit) (*domain.Return, error)
+	ChangeItems(ctx context.Context, id uuid.UUID, version int64, changes []domain.LineChange, replace bool, audit ordersvc.Audit) (*domain.Order, error)
+	Holds(ctx context.Context, id uuid.UUID) ([]domain.Hold, error)
+	PlaceHold(ctx context.Context, id uuid.UUID, version int64, reason domain.HoldReason, note string, audit ordersvc.Audit) (*domain.Order, *domain.Hold, error)
+	ReleaseHold(ctx context.Context, id, holdID uuid.UUID, version int64, audit ordersvc.Audit) (*domain.Order, *domain.Hold, error)
+	ReceiveReturn(ctx context.Context, id, returnID uuid.UUID, audit ordersvc.Audit) (*domain.Return, error)
 }
 
 type Handler struct {
@@ -38,9 +55,21 @@ func NewHandler(svc Service, logger *log.Logger, idem *idempotency.Store, sg *sa
 }
 
 type createReq struct {
-	CustomerID string        `json:"customer_id"`
-	Currency   string        `json:"currency"`
-	Items      []domain.Item `json:"items"`
+	CustomerID      string          `json:"customer_id"`
+	Currency        string          `json:"currency"`
+	Items           []createItem    `json:"items"`
+	ShippingAddress *domain.Address `json:"shipping_address,omitempty"`
+	BillingAddress  *domain.Address `json:"billing_address,omitempty"`
+	PromoCodes      []string        `json:"promo_codes,omitempty"`
+}
+
+// createItem is an order line as sent by clients. Prices come from the
+// catalog; price_minor is still accepted so older clients keep working, but
+// it is ignored.
+type createItem struct {
+	SKU        string `json:"sku"`
+	Quantity   int    `json:"quantity"`
+	PriceMinor int64  `json:"price_minor,omitempty"`
 }
 
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
@@ -50,7 +79,7 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		respond.Error(w, http.StatusBadRequest, "invalid json")
 		return
 	}
-	if !currencyRe.MatchString(req.Currency) {
+	if !validCurrency(req.Currency) {
 		respond.Error(w, http.StatusBadRequest, "invalid currency")
 		return
 	}
@@ -65,6 +94,7 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	if key != "" {
 		if res, err := h.idem.Get(r.Context(), key, route); err == nil && res.Found {
 			if o, err := h.svc.Get(r.Context(), res.OrderID); err == nil && o != nil {
+				setETag(w, o.Version)
 				respond.JSON(w, res.Status, o)
 				return
 			}
@@ -74,10 +104,20 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
 	defer cancel()
 
-	o, err := h.svc.Create(ctx, cid, req.Currency, req.Items)
+	items := make([]domain.Item, len(req.Items))
+	for i, it := range req.Items {
+		items[i] = domain.Item{SKU: it.SKU, Quantity: it.Quantity}
+	}
+	o, err := h.svc.Create(ctx, ordersvc.CreateCmd{
+		CustomerID:      cid,
+		Currency:        req.Currency,
+		Items:           items,
+		ShippingAddress: req.ShippingAddress,
+		BillingAddress:  req.BillingAddress,
+		PromoCodes:      req.PromoCodes,
+	}, auditFrom(r, ""))
 	if err != nil {
-		h.log.Error("failed to create order: %v", log.Err(err))
-		respond.Error(w, http.StatusBadRequest, err.Error())
+		h.fail(w, err)
 		return
 	}
 	if key != "" {
@@ -92,7 +132,7 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 			{StepNo: 1, Name: "reserve-inventory", Action: "reserve_inventory", Compensate: "release_inventory",
 				Payload: map[string]any{"order_id": o.ID.String()}},
 			{StepNo: 2, Name: "authorize-payment", Action: "authorize_payment", Compensate: "void_payment",
-				Payload: map[string]any{"order_id": o.ID.String(), "amount_minor": o.TotalAmount}},
+				Payload: map[string]any{"order_id": o.ID.String(), "amount_minor": o.TotalAmount.Amount, "currency": o.Currency}},
 		}
 		_, err = h.sg.Store().Create(r.Context(), "order-fulfillment", steps, map[string]any{"order_id": o.ID.String()})
 		if err != nil {
@@ -100,6 +140,7 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	setETag(w, o.Version)
 	respond.JSON(w, http.StatusCreated, o)
 }
 
@@ -116,29 +157,26 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 
 	o, err := h.svc.Get(ctx, id)
 	if err != nil {
-		respond.Error(w, http.StatusNotFound, "not found")
+		h.fail(w, err)
 		return
 	}
+	setETag(w, o.Version)
 	respond.JSON(w, http.StatusOK, o)
 }
 
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
-	cursor := r.URL.Query().Get("cursor")
-	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
-	if err != nil || limit <= 0 {
-		limit = 20
-	}
-	if limit > 100 {
-		limit = 100
+	params, err := parseListParams(r.URL.Query())
+	if err != nil {
+		respond.Error(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 	defer cancel()
 
-	page, err := h.svc.List(ctx, limit, cursor)
+	page, err := h.svc.List(ctx, params)
 	if err != nil {
-		h.log.Error("failed to list orders: %v", log.Err(err))
-		respond.Error(w, http.StatusInternalServerError, err.Error())
+		h.fail(w, err)
 		return
 	}
 	respond.JSON(w, http.StatusOK, page)
@@ -146,6 +184,7 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 
 type patchStatusReq struct {
 	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
 }
 
 func (h *Handler) PatchStatus(w http.ResponseWriter, r *http.Request) {
@@ -155,6 +194,11 @@ func (h *Handler) PatchStatus(w http.ResponseWriter, r *http.Request) {
 		respond.Error(w, http.StatusBadRequest, "invalid id")
 		return
 	}
+	version, ok := ifMatchVersion(r)
+	if !ok {
+		respond.Error(w, http.StatusPreconditionRequired, "If-Match header with the order ETag is required")
+		return
+	}
 	var req patchStatusReq
 	if err := request.DecodeJSON(w, r, &req); err != nil || req.Status == "" {
 		h.log.Error("failed to decode body: %v", log.Err(err))
@@ -178,11 +222,73 @@ func (h *Handler) PatchStatus(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 	defer cancel()
 
-	if err := h.svc.UpdateStatus(ctx, id, target); err != nil {
-		respond.Error(w, http.StatusBadRequest, err.Error())
+	o, err := h.svc.UpdateStatus(ctx, id, target, version, auditFrom(r, req.Reason))
+	if err != nil {
+		h.fail(w, err)
 		return
 	}
-	respond.JSON(w, http.StatusOK, map[string]string{"status": string(target)})
+	setETag(w, o.Version)
+	respond.JSON(w, http.StatusOK, map[string]string{"status": string(o.Status)})
+}
+
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chiURLParam(r, "id"))
+	if err != nil {
+		h.log.Error("failed to parse id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	history, err := h.svc.History(ctx, id)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, map[string]any{"history": history})
+}
+
+// auditFrom attributes a change to the authenticated subject and request ID.
+func auditFrom(r *http.Request, reason string) ordersvc.Audit {
+	return ordersvc.Audit{
+		Actor:     auth.Subject(r.Context()),
+		Reason:    reason,
+		RequestID: reqIDFromCtx(r.Context()),
+	}
+}
+
+// fail maps domain and service errors onto HTTP status codes.
+func (h *Handler) fail(w http.ResponseWriter, err error) {
+	var (
+		te  *domain.TransitionError
+		rte *domain.ReturnTransitionError
+		ve  *domain.ValidationError
+		le  *ordersvc.LimitError
+	)
+	switch {
+	case errors.As(err, &le):
+		limitExceeded(w, le)
+	case errors.Is(err, domain.ErrNotFound), errors.Is(err, domain.ErrReturnNotFound), errors.Is(err, domain.ErrHoldNotFound):
+		respond.Error(w, http.StatusNotFound, "not found")
+	case errors.As(err, &te):
+		respond.Error(w, http.StatusConflict, te.Error())
+	case errors.As(err, &rte):
+		respond.Error(w, http.StatusConflict, rte.Error())
+	case errors.As(err, &ve):
+		respond.Error(w, http.StatusUnprocessableEntity, ve.Error())
+	case errors.Is(err, ordersvc.ErrInvalidCursor):
+		respond.Error(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrNotModifiable):
+		respond.Error(w, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ErrVersionConflict):
+		respond.Error(w, http.StatusPreconditionFailed, "order was modified, refetch and retry")
+	case errors.Is(err, context.DeadlineExceeded):
+		respond.Error(w, http.StatusGatewayTimeout, "timeout")
+	default:
+		h.log.Error("request failed", log.Err(err))
+		respond.Error(w, http.StatusInternalServerError, "internal error")
+	}
 }
 
 // --- tiny shims to decouple router from handler for tests ---
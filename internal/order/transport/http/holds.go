@@ -0,0 +1,110 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/request"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/respond"
+	"github.com/google/uuid"
+)
+
+type placeHoldReq struct {
+	Reason domain.HoldReason `json:"reason"`
+	Note   string            `json:"note,omitempty"`
+}
+
+type releaseHoldReq struct {
+	Note string `json:"note,omitempty"`
+}
+
+func (h *Handler) ListHolds(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chiURLParam(r, "id"))
+	if err != nil {
+		h.log.Error("failed to parse id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	holds, err := h.svc.Holds(ctx, id)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, map[string]any{"holds": holds})
+}
+
+func (h *Handler) PlaceHold(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chiURLParam(r, "id"))
+	if err != nil {
+		h.log.Error("failed to parse id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	version, ok := optionalIfMatch(r)
+	if !ok {
+		respond.Error(w, http.StatusBadRequest, "invalid If-Match header")
+		return
+	}
+	var req placeHoldReq
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		h.log.Error("failed to decode body: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	o, hold, err := h.svc.PlaceHold(ctx, id, version, req.Reason, req.Note, auditFrom(r, req.Note))
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	setETag(w, o.Version)
+	respond.JSON(w, http.StatusCreated, map[string]any{"status": o.Status, "hold": hold})
+}
+
+func (h *Handler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chiURLParam(r, "id"))
+	if err != nil {
+		h.log.Error("failed to parse id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	holdID, err := uuid.Parse(chiURLParam(r, "holdID"))
+	if err != nil {
+		h.log.Error("failed to parse hold id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid hold id")
+		return
+	}
+	version, ok := optionalIfMatch(r)
+	if !ok {
+		respond.Error(w, http.StatusBadRequest, "invalid If-Match header")
+		return
+	}
+	var req releaseHoldReq
+	if r.ContentLength != 0 {
+		if err := request.DecodeJSON(w, r, &req); err != nil {
+			h.log.Error("failed to decode body: %v", log.Err(err))
+			respond.Error(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	o, hold, err := h.svc.ReleaseHold(ctx, id, holdID, version, auditFrom(r, req.Note))
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	setETag(w, o.Version)
+	respond.JSON(w, http.StatusOK, map[string]any{"status": o.Status, "hold": hold})
+}
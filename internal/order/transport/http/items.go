@@ -0,0 +1,71 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/request"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/respond"
+	"github.com/google/uuid"
+)
+
+// itemChange names an existing line by line_id, or adds a line of sku when
+// line_id is omitted.
+type itemChange struct {
+	LineID   uuid.UUID `json:"line_id,omitempty"`
+	SKU      string    `json:"sku,omitempty"`
+	Quantity int       `json:"quantity"`
+}
+
+type changeItemsReq struct {
+	Items []itemChange `json:"items"`
+}
+
+// ReplaceItems handles PUT: the body is the complete new line set.
+func (h *Handler) ReplaceItems(w http.ResponseWriter, r *http.Request) {
+	h.changeItems(w, r, true)
+}
+
+// PatchItems handles PATCH: only the named lines change, quantity 0 removes
+// a line.
+func (h *Handler) PatchItems(w http.ResponseWriter, r *http.Request) {
+	h.changeItems(w, r, false)
+}
+
+func (h *Handler) changeItems(w http.ResponseWriter, r *http.Request, replace bool) {
+	id, err := uuid.Parse(chiURLParam(r, "id"))
+	if err != nil {
+		h.log.Error("failed to parse id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	version, ok := optionalIfMatch(r)
+	if !ok {
+		respond.Error(w, http.StatusBadRequest, "invalid If-Match header")
+		return
+	}
+	var req changeItemsReq
+	if err := request.DecodeJSON(w, r, &req); err != nil || len(req.Items) == 0 {
+		h.log.Error("failed to decode body: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	changes := make([]domain.LineChange, len(req.Items))
+	for i, it := range req.Items {
+		changes[i] = domain.LineChange{LineID: it.LineID, SKU: it.SKU, Quantity: it.Quantity}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	o, err := h.svc.ChangeItems(ctx, id, version, changes, replace, auditFrom(r, ""))
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	setETag(w, o.Version)
+	respond.JSON(w, http.StatusOK, o)
+}
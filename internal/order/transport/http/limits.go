@@ -0,0 +1,32 @@
+package http
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	ordersvc "github.com/GolangDeveloperAlmir/order-service/internal/order/service"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/respond"
+)
+
+const limitProblemType = "urn:order-service:problem:customer-limit"
+
+// limitExceeded answers an order refused by a customer limit: 429 with
+// Retry-After for limits that reset over time, 422 for open orders, which
+// only free up as orders are fulfilled or cancelled.
+func limitExceeded(w http.ResponseWriter, err *ordersvc.LimitError) {
+	p := respond.ProblemDetails{
+		Type:       limitProblemType,
+		Title:      "Customer order limit exceeded",
+		Status:     http.StatusUnprocessableEntity,
+		Detail:     err.Error(),
+		Extensions: map[string]any{"limit": err.Limit, "max": err.Max},
+	}
+	if err.RetryAfter > 0 {
+		secs := int64(math.Ceil(err.RetryAfter.Seconds()))
+		p.Status = http.StatusTooManyRequests
+		p.Extensions["retry_after"] = secs
+		w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+	}
+	respond.Problem(w, p)
+}
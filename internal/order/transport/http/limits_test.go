@@ -0,0 +1,55 @@
+package http
+
+import (
+	"encoding/json"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	ordersvc "github.com/GolangDeveloperAlmir/order-service/internal/order/service"
+	"go.uber.org/zap"
+)
+
+func TestLimitExceededProblem(t *testing.T) {
+	rec := httptest.NewRecorder()
+	limitExceeded(rec, &ordersvc.LimitError{Limit: ordersvc.LimitOrdersPerHour, Max: 5, RetryAfter: 90*time.Second + time.Millisecond})
+	if rec.Code != stdhttp.StatusTooManyRequests || rec.Header().Get("Retry-After") != "91" {
+		t.Fatalf("hourly: code=%d retry-after=%q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("content type %q", ct)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["type"] != limitProblemType || body["status"] != float64(429) || body["limit"] != ordersvc.LimitOrdersPerHour || body["detail"] == "" {
+		t.Fatalf("body: %v", body)
+	}
+
+	rec = httptest.NewRecorder()
+	limitExceeded(rec, &ordersvc.LimitError{Limit: ordersvc.LimitOpenOrders, Max: 3})
+	if rec.Code != stdhttp.StatusUnprocessableEntity || rec.Header().Get("Retry-After") != "" {
+		t.Fatalf("open orders: code=%d retry-after=%q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestFailMapsCreateErrors(t *testing.T) {
+	h := NewHandler(nil, zap.NewNop(), nil, nil)
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{&domain.ValidationError{Msg: "unknown currency XXX"}, stdhttp.StatusUnprocessableEntity},
+		{domain.ErrRiskRejected, stdhttp.StatusUnprocessableEntity},
+		{&ordersvc.LimitError{Limit: ordersvc.LimitValuePerDay, Max: 100, RetryAfter: time.Hour}, stdhttp.StatusTooManyRequests},
+	} {
+		rec := httptest.NewRecorder()
+		h.fail(rec, tc.err)
+		if rec.Code != tc.code {
+			t.Fatalf("%v: code=%d, want %d", tc.err, rec.Code, tc.code)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	ordersvc "github.com/GolangDeveloperAlmir/order-service/internal/order/service"
+	"github.com/google/uuid"
+)
+
+var listStatuses = map[string]domain.Status{
+	string(domain.StatusCreated):           domain.StatusCreated,
+	string(domain.StatusPaid):              domain.StatusPaid,
+	string(domain.StatusCancelled):         domain.StatusCancelled,
+	string(domain.StatusPartiallyShipped):  domain.StatusPartiallyShipped,
+	string(domain.StatusShipped):           domain.StatusShipped,
+	string(domain.StatusPartiallyRefunded): domain.StatusPartiallyRefunded,
+	string(domain.StatusRefunded):          domain.StatusRefunded,
+	string(domain.StatusOnHold):            domain.StatusOnHold,
+}
+
+// parseListParams reads the GET /api/v1/orders query string. status may be
+// repeated or comma separated; sort is created_at (default) or total_amount
+// and order is asc (default) or desc.
+func parseListParams(q url.Values) (ordersvc.ListParams, error) {
+	p := ordersvc.ListParams{Cursor: q.Get("cursor"), Sort: ordersvc.SortCreatedAt}
+
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	p.Limit = limit
+
+	if v := q.Get("customer_id"); v != "" {
+		if p.CustomerID, err = uuid.Parse(v); err != nil {
+			return p, errors.New("invalid customer_id")
+		}
+	}
+	for _, v := range q["status"] {
+		for _, s := range strings.Split(v, ",") {
+			st, ok := listStatuses[strings.TrimSpace(s)]
+			if !ok {
+				return p, errors.New("invalid status")
+			}
+			p.Statuses = append(p.Statuses, st)
+		}
+	}
+	if v := q.Get("currency"); v != "" {
+		if !validCurrency(v) {
+			return p, errors.New("invalid currency")
+		}
+		p.Currency = v
+	}
+	if p.CreatedFrom, err = parseTimeParam(q, "created_from"); err != nil {
+		return p, err
+	}
+	if p.CreatedTo, err = parseTimeParam(q, "created_to"); err != nil {
+		return p, err
+	}
+	if p.MinTotal, err = parseAmountParam(q, "min_total"); err != nil {
+		return p, err
+	}
+	if p.MaxTotal, err = parseAmountParam(q, "max_total"); err != nil {
+		return p, err
+	}
+
+	switch q.Get("sort") {
+	case "", string(ordersvc.SortCreatedAt):
+	case string(ordersvc.SortTotalAmount):
+		p.Sort = ordersvc.SortTotalAmount
+	default:
+		return p, errors.New("invalid sort")
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		p.Desc = true
+	default:
+		return p, errors.New("invalid order")
+	}
+
+	return p, nil
+}
+
+func parseTimeParam(q url.Values, name string) (time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, errors.New("invalid " + name)
+	}
+
+	return t, nil
+}
+
+func parseAmountParam(q url.Values, name string) (*int64, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return nil, errors.New("invalid " + name)
+	}
+
+	return &n, nil
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/request"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/respond"
+	"github.com/google/uuid"
+)
+
+type createRefundReq struct {
+	AmountMinor int64       `json:"amount_minor"`
+	LineIDs     []uuid.UUID `json:"line_ids,omitempty"`
+	Reason      string      `json:"reason,omitempty"`
+}
+
+func (h *Handler) CreateRefund(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chiURLParam(r, "id"))
+	if err != nil {
+		h.log.Error("failed to parse id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	version, ok := optionalIfMatch(r)
+	if !ok {
+		respond.Error(w, http.StatusBadRequest, "invalid If-Match header")
+		return
+	}
+	var req createRefundReq
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		h.log.Error("failed to decode body: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	o, rf, err := h.svc.CreateRefund(ctx, id, version, req.AmountMinor, req.LineIDs, req.Reason, auditFrom(r, req.Reason))
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	setETag(w, o.Version)
+	respond.JSON(w, http.StatusCreated, map[string]any{
+		"status":          o.Status,
+		"refund_status":   o.RefundStatus,
+		"refunded_amount": o.RefundedAmount,
+		"refund":          rf,
+	})
+}
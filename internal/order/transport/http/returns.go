@@ -0,0 +1,113 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	ordersvc "github.com/GolangDeveloperAlmir/order-service/internal/order/service"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/request"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/respond"
+	"github.com/google/uuid"
+)
+
+type requestReturnReq struct {
+	Lines  []domain.ReturnLine `json:"lines"`
+	Reason string              `json:"reason,omitempty"`
+}
+
+type returnDecisionReq struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+func (h *Handler) ListReturns(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chiURLParam(r, "id"))
+	if err != nil {
+		h.log.Error("failed to parse id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	returns, err := h.svc.Returns(ctx, id)
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, map[string]any{"returns": returns})
+}
+
+func (h *Handler) RequestReturn(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chiURLParam(r, "id"))
+	if err != nil {
+		h.log.Error("failed to parse id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req requestReturnReq
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		h.log.Error("failed to decode body: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	rt, err := h.svc.RequestReturn(ctx, id, req.Lines, req.Reason, auditFrom(r, req.Reason))
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusCreated, rt)
+}
+
+func (h *Handler) ApproveReturn(w http.ResponseWriter, r *http.Request) {
+	h.decideReturn(w, r, Service.ApproveReturn)
+}
+
+func (h *Handler) RejectReturn(w http.ResponseWriter, r *http.Request) {
+	h.decideReturn(w, r, Service.RejectReturn)
+}
+
+// ReceiveReturn marks a return received; the service starts the
+// return-processing saga that restocks and refunds it.
+func (h *Handler) ReceiveReturn(w http.ResponseWriter, r *http.Request) {
+	h.decideReturn(w, r, Service.ReceiveReturn)
+}
+
+func (h *Handler) decideReturn(w http.ResponseWriter, r *http.Request, decide func(svc Service, ctx context.Context, id, returnID uuid.UUID, audit ordersvc.Audit) (*domain.Return, error)) {
+	id, err := uuid.Parse(chiURLParam(r, "id"))
+	if err != nil {
+		h.log.Error("failed to parse id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	returnID, err := uuid.Parse(chiURLParam(r, "returnID"))
+	if err != nil {
+		h.log.Error("failed to parse return id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid return id")
+		return
+	}
+	var req returnDecisionReq
+	if r.ContentLength != 0 {
+		if err := request.DecodeJSON(w, r, &req); err != nil {
+			h.log.Error("failed to decode body: %v", log.Err(err))
+			respond.Error(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	rt, err := decide(h.svc, ctx, id, returnID, auditFrom(r, req.Reason))
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, rt)
+}
@@ -52,34 +52,43 @@ func NewRouter(h *Handler, logger *log.Logger, opts ...RouterOpt) stdhttp.Handle
 		stdhttp.ServeFile(w, r, "openapi.yaml")
 	})
 
-	// Public reads
+	// Writes are protected if Auth middleware is provided; reads stay public.
+	protect := cfg.AuthMW
+	if protect == nil {
+		protect = func(next stdhttp.Handler) stdhttp.Handler { return next }
+	}
+
 	r.Route("/api/v1/orders", func(r chi.Router) {
 		r.Get("/", h.List)
+		r.With(protect).Post("/", h.Create)
+
 		r.Route("/{id}", func(r chi.Router) {
 			r.Use(bindIDParam("id"))
 			r.Get("/", h.Get)
-		})
-	})
-
-	// Protected writes (if Auth middleware provided)
-	if cfg.AuthMW != nil {
-		r.Group(func(r chi.Router) {
-			r.Use(cfg.AuthMW)
-			r.Route("/api/v1/orders", func(r chi.Router) {
-				r.Post("/", h.Create)
-				r.Route("/{id}", func(r chi.Router) {
-					r.Use(bindIDParam("id"))
-					r.Patch("/", h.PatchStatus)
+			r.Get("/history", h.History)
+			r.Get("/returns", h.ListReturns)
+			r.Get("/holds", h.ListHolds)
+
+			r.Group(func(r chi.Router) {
+				r.Use(protect)
+				r.Patch("/", h.PatchStatus)
+				r.Patch("/addresses", h.ChangeAddresses)
+				r.Put("/items", h.ReplaceItems)
+				r.Patch("/items", h.PatchItems)
+				r.Post("/shipments", h.CreateShipment)
+				r.Post("/refunds", h.CreateRefund)
+				r.Post("/returns", h.RequestReturn)
+				r.Route("/returns/{returnID}", func(r chi.Router) {
+					r.Use(bindIDParam("returnID"))
+					r.Post("/approve", h.ApproveReturn)
+					r.Post("/reject", h.RejectReturn)
+					r.Post("/receive", h.ReceiveReturn)
 				})
+				r.Post("/holds", h.PlaceHold)
+				r.With(bindIDParam("holdID")).Post("/holds/{holdID}/release", h.ReleaseHold)
 			})
 		})
-	} else {
-		r.Post("/api/v1/orders", h.Create)
-		r.Route("/api/v1/orders/{id}", func(r chi.Router) {
-			r.Use(bindIDParam("id"))
-			r.Patch("/", h.PatchStatus)
-		})
-	}
+	})
 
 	return r
 }
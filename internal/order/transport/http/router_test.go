@@ -0,0 +1,45 @@
+package http
+
+import (
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestRouterMountsOrderRoutes(t *testing.T) {
+	passthrough := func(next stdhttp.Handler) stdhttp.Handler { return next }
+
+	for _, opts := range [][]RouterOpt{nil, {WithAuth(passthrough)}} {
+		h := NewHandler(nil, zap.NewNop(), nil, nil)
+		r := NewRouter(h, zap.NewNop(), opts...)
+
+		// An unparsable id is rejected by the handler itself, which proves the
+		// request was routed rather than answered with 404/405 by chi.
+		for _, tc := range []struct{ method, path string }{
+			{stdhttp.MethodGet, "/api/v1/orders/not-a-uuid"},
+			{stdhttp.MethodGet, "/api/v1/orders/not-a-uuid/history"},
+			{stdhttp.MethodPatch, "/api/v1/orders/not-a-uuid"},
+			{stdhttp.MethodPatch, "/api/v1/orders/not-a-uuid/addresses"},
+			{stdhttp.MethodPost, "/api/v1/orders/not-a-uuid/shipments"},
+			{stdhttp.MethodPost, "/api/v1/orders/not-a-uuid/refunds"},
+			{stdhttp.MethodGet, "/api/v1/orders/not-a-uuid/returns"},
+			{stdhttp.MethodPost, "/api/v1/orders/not-a-uuid/returns"},
+			{stdhttp.MethodPost, "/api/v1/orders/8c0a3f3e-9a43-4bb4-9d7e-1f4f3b0b8a11/returns/not-a-uuid/approve"},
+			{stdhttp.MethodPost, "/api/v1/orders/8c0a3f3e-9a43-4bb4-9d7e-1f4f3b0b8a11/returns/not-a-uuid/reject"},
+			{stdhttp.MethodPost, "/api/v1/orders/8c0a3f3e-9a43-4bb4-9d7e-1f4f3b0b8a11/returns/not-a-uuid/receive"},
+			{stdhttp.MethodPut, "/api/v1/orders/not-a-uuid/items"},
+			{stdhttp.MethodPatch, "/api/v1/orders/not-a-uuid/items"},
+			{stdhttp.MethodGet, "/api/v1/orders/not-a-uuid/holds"},
+			{stdhttp.MethodPost, "/api/v1/orders/not-a-uuid/holds"},
+			{stdhttp.MethodPost, "/api/v1/orders/8c0a3f3e-9a43-4bb4-9d7e-1f4f3b0b8a11/holds/not-a-uuid/release"},
+		} {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			if rec.Code != stdhttp.StatusBadRequest {
+				t.Errorf("auth=%v %s %s: got %d want 400", opts != nil, tc.method, tc.path, rec.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/request"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/respond"
+	"github.com/google/uuid"
+)
+
+type createShipmentReq struct {
+	Lines          []domain.ShipmentLine `json:"lines"`
+	Carrier        string                `json:"carrier"`
+	TrackingNumber string                `json:"tracking_number"`
+}
+
+func (h *Handler) CreateShipment(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chiURLParam(r, "id"))
+	if err != nil {
+		h.log.Error("failed to parse id: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	version, ok := optionalIfMatch(r)
+	if !ok {
+		respond.Error(w, http.StatusBadRequest, "invalid If-Match header")
+		return
+	}
+	var req createShipmentReq
+	if err := request.DecodeJSON(w, r, &req); err != nil {
+		h.log.Error("failed to decode body: %v", log.Err(err))
+		respond.Error(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	o, sh, err := h.svc.CreateShipment(ctx, id, version, req.Lines, req.Carrier, req.TrackingNumber, auditFrom(r, ""))
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+	setETag(w, o.Version)
+	respond.JSON(w, http.StatusCreated, map[string]any{"status": o.Status, "shipment": sh})
+}
@@ -0,0 +1,18 @@
+package auth
+
+import "context"
+
+type ctxKey struct{}
+
+// WithSubject stores the authenticated subject in ctx.
+func WithSubject(ctx context.Context, sub string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, sub)
+}
+
+// Subject returns the authenticated subject, or "" for anonymous requests.
+func Subject(ctx context.Context) string {
+	if v, ok := ctx.Value(ctxKey{}).(string); ok {
+		return v
+	}
+	return ""
+}
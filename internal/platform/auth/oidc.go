@@ -71,7 +71,8 @@ func (m *OIDC) Middleware(next http.Handler) http.Handler {
 			http.Error(w, "insufficient scope", http.StatusForbidden)
 			return
 		}
-		next.ServeHTTP(w, r)
+		sub, _ := claims["sub"].(string)
+		next.ServeHTTP(w, r.WithContext(WithSubject(r.Context(), sub)))
 	})
 }
 
@@ -84,13 +85,13 @@ func bearer(h string) string {
 }
 
 func hasScope(claims map[string]any, want string) bool {
-        if v, ok := claims["scope"].(string); ok {
-                for _, s := range strings.Split(v, " ") {
-                        if s == want {
-                                return true
-                        }
-                }
-        }
+	if v, ok := claims["scope"].(string); ok {
+		for _, s := range strings.Split(v, " ") {
+			if s == want {
+				return true
+			}
+		}
+	}
 	if arr, ok := claims["scp"].([]any); ok {
 		for _, s := range arr {
 			if sstr, ok := s.(string); ok && sstr == want {
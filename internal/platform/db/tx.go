@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"errors"
+
 	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
 
 	"github.com/jackc/pgx/v5"
@@ -13,9 +15,10 @@ type TxManager struct {
 	log  *log.Logger
 }
 
-func NewTxManager(pool *pgxpool.Pool) *TxManager {
+func NewTxManager(pool *pgxpool.Pool, logger *log.Logger) *TxManager {
 	return &TxManager{
 		pool: pool,
+		log:  logger,
 	}
 }
 
@@ -26,13 +29,12 @@ func (t *TxManager) InTx(ctx context.Context, fn func(pgx.Tx) error) error {
 		return err
 	}
 	defer func() {
-		if err := tx.Rollback(ctx); err != nil {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
 			t.log.Error("failed to rollback tx", log.Err(err))
 		}
 	}()
 
 	if err := fn(tx); err != nil {
-		t.log.Error("failed to execute tx", log.Err(err))
 		return err
 	}
 
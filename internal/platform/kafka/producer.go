@@ -15,10 +15,11 @@ type Producer struct {
 	log    *log.Logger
 }
 
-func NewProducer(brokersCSV, topic string) *Producer {
+func NewProducer(brokersCSV, topic string, logger *log.Logger) *Producer {
 	brokers := strings.Split(brokersCSV, ",")
 
 	return &Producer{
+		log: logger,
 		writer: &k.Writer{
 			Addr:         k.TCP(brokers...),
 			Topic:        topic,
@@ -34,14 +35,15 @@ func (p *Producer) Close() error {
 	return p.writer.Close()
 }
 
-func (p *Producer) Publish(ctx context.Context, key string, value []byte) error {
-	p.log.Info("Producer write message")
+// Publish writes values under key in a single WriteMessages call, so they
+// land on one partition in order.
+func (p *Producer) Publish(ctx context.Context, key string, values ...[]byte) error {
+	p.log.Debug("Producer write messages", log.Int("count", len(values)))
 
-	return p.writer.WriteMessages(
-		ctx,
-		k.Message{
-			Key:   []byte(key),
-			Value: value,
-		},
-	)
+	msgs := make([]k.Message, len(values))
+	for i, v := range values {
+		msgs[i] = k.Message{Key: []byte(key), Value: v}
+	}
+
+	return p.writer.WriteMessages(ctx, msgs...)
 }
@@ -0,0 +1,241 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// State is where an outbox event is in its life.
+type State string
+
+const (
+	StatePending   State = "pending"
+	StatePublished State = "published"
+	StateDead      State = "dead"
+)
+
+// stateCond is the SQL condition selecting the rows in a state.
+var stateCond = map[State]string{
+	StatePending:   "published_at IS NULL AND dead_at IS NULL",
+	StatePublished: "published_at IS NOT NULL",
+	StateDead:      "dead_at IS NOT NULL",
+}
+
+// ErrInvalidSelector is returned for admin requests that select nothing or,
+// for destructive actions, everything.
+var ErrInvalidSelector = errors.New("invalid selector")
+
+// Event is an outbox row as shown to operators.
+type Event struct {
+	ID            int64           `json:"id"`
+	State         State           `json:"state"`
+	AggregateID   uuid.UUID       `json:"aggregate_id"`
+	AggregateType string          `json:"aggregate_type"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+	AvailableAt   time.Time       `json:"available_at"`
+	PublishedAt   *time.Time      `json:"published_at,omitempty"`
+	DeadAt        *time.Time      `json:"dead_at,omitempty"`
+	FailCount     int             `json:"fail_count"`
+	LastError     string          `json:"last_error,omitempty"`
+}
+
+// Filter narrows List. Empty fields match everything; rows come in id order
+// after AfterID.
+type Filter struct {
+	State       State
+	EventType   string
+	AggregateID uuid.UUID
+	AfterID     int64
+	Limit       int
+}
+
+// Selector picks the events an admin action applies to: those in State with
+// an id in [FromID, ToID] and created in [From, To). Zero bounds are open,
+// but at least one must be set.
+type Selector struct {
+	State  State     `json:"state"`
+	FromID int64     `json:"from_id,omitempty"`
+	ToID   int64     `json:"to_id,omitempty"`
+	From   time.Time `json:"from,omitempty"`
+	To     time.Time `json:"to,omitempty"`
+}
+
+// where returns the SQL condition for s and its arguments, numbered from
+// $1.
+func (s Selector) where(states ...State) (string, []any, error) {
+	cond, ok := stateCond[s.State]
+	valid := false
+	for _, st := range states {
+		valid = valid || st == s.State
+	}
+	if !ok || !valid {
+		return "", nil, fmt.Errorf("%w: state must be one of %v", ErrInvalidSelector, states)
+	}
+	if s.FromID == 0 && s.ToID == 0 && s.From.IsZero() && s.To.IsZero() {
+		return "", nil, fmt.Errorf("%w: an id range or time window is required", ErrInvalidSelector)
+	}
+	where := []string{cond}
+	var args []any
+	add := func(c string, v any) {
+		args = append(args, v)
+		where = append(where, strings.ReplaceAll(c, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if s.FromID != 0 {
+		add("id >= ?", s.FromID)
+	}
+	if s.ToID != 0 {
+		add("id <= ?", s.ToID)
+	}
+	if !s.From.IsZero() {
+		add("created_at >= ?", s.From)
+	}
+	if !s.To.IsZero() {
+		add("created_at < ?", s.To)
+	}
+	return strings.Join(where, " AND "), args, nil
+}
+
+// Admin lets operators inspect, re-drive and purge outbox events. Every
+// change is recorded in outbox_admin_audit in the same transaction.
+//
+// It is safe to use while the relay runs: the relay only locks pending
+// rows, and actions only touch published or dead ones. A row the relay
+// publishes while an action waits on its lock is re-evaluated by Postgres
+// before it is changed.
+type Admin struct {
+	pool *pgxpool.Pool
+	log  *log.Logger
+}
+
+func NewAdmin(pool *pgxpool.Pool, logger *log.Logger) *Admin {
+	return &Admin{pool: pool, log: logger}
+}
+
+// List returns the events matching f, at most f.Limit (default 100, max
+// 1000).
+func (a *Admin) List(ctx context.Context, f Filter) ([]Event, error) {
+	where := []string{"id > $1"}
+	args := []any{f.AfterID}
+	if f.State != "" {
+		cond, ok := stateCond[f.State]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown state %q", ErrInvalidSelector, f.State)
+		}
+		where = append(where, cond)
+	}
+	if f.EventType != "" {
+		args = append(args, f.EventType)
+		where = append(where, "event_type = $"+strconv.Itoa(len(args)))
+	}
+	if f.AggregateID != uuid.Nil {
+		args = append(args, f.AggregateID)
+		where = append(where, "aggregate_id = $"+strconv.Itoa(len(args)))
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	args = append(args, min(limit, 1000))
+
+	rows, err := a.pool.Query(ctx, `
+		SELECT id, aggregate_id, aggregate_type, event_type, payload, created_at, available_at, published_at, dead_at, fail_count, COALESCE(last_error, '')
+		FROM outbox
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id
+		LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		a.log.Error("failed to list outbox", log.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.AggregateID, &e.AggregateType, &e.EventType, &e.Payload, &e.CreatedAt, &e.AvailableAt, &e.PublishedAt, &e.DeadAt, &e.FailCount, &e.LastError); err != nil {
+			a.log.Error("failed to scan outbox", log.Err(err))
+			return nil, err
+		}
+		switch {
+		case e.DeadAt != nil:
+			e.State = StateDead
+		case e.PublishedAt != nil:
+			e.State = StatePublished
+		default:
+			e.State = StatePending
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		a.log.Error("failed to list outbox", log.Err(err))
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// Redrive queues dead or published events for publishing again, with a fresh
+// attempt count, and returns how many were queued.
+func (a *Admin) Redrive(ctx context.Context, s Selector, actor, reason string) (int64, error) {
+	where, args, err := s.where(StateDead, StatePublished)
+	if err != nil {
+		return 0, err
+	}
+	return a.apply(ctx, "redrive", s, actor, reason, `
+		UPDATE outbox
+		SET published_at = NULL, dead_at = NULL, fail_count = 0, last_error = NULL, available_at = now()
+		WHERE `+where, args)
+}
+
+// Purge deletes published or dead events and returns how many were deleted.
+// Pending events are never purged.
+func (a *Admin) Purge(ctx context.Context, s Selector, actor, reason string) (int64, error) {
+	where, args, err := s.where(StatePublished, StateDead)
+	if err != nil {
+		return 0, err
+	}
+	return a.apply(ctx, "purge", s, actor, reason, `DELETE FROM outbox WHERE `+where, args)
+}
+
+func (a *Admin) apply(ctx context.Context, action string, s Selector, actor, reason, stmt string, args []any) (int64, error) {
+	if strings.TrimSpace(actor) == "" {
+		return 0, fmt.Errorf("%w: actor is required", ErrInvalidSelector)
+	}
+	var n int64
+	err := pgx.BeginFunc(ctx, a.pool, func(tx pgx.Tx) error {
+		ct, err := tx.Exec(ctx, stmt, args...)
+		if err != nil {
+			return err
+		}
+		n = ct.RowsAffected()
+		if action == "redrive" && n > 0 {
+			// the notify trigger only fires on inserts
+			if _, err := tx.Exec(ctx, `SELECT pg_notify($1, '')`, Channel); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO outbox_admin_audit (action, actor, reason, selector, affected)
+			VALUES ($1,$2,$3,$4,$5)`, action, actor, reason, s, n)
+		return err
+	})
+	if err != nil {
+		a.log.Error("outbox admin action failed", log.Str("action", action), log.Err(err))
+		return 0, err
+	}
+	a.log.Info("outbox admin action", log.Str("action", action), log.Str("actor", actor), log.Str("reason", reason), log.Int("affected", int(n)))
+
+	return n, nil
+}
@@ -0,0 +1,97 @@
+package outbox
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/GolangDeveloperAlmir/order-service/pkg/request"
+	"github.com/GolangDeveloperAlmir/order-service/pkg/respond"
+	"github.com/google/uuid"
+)
+
+type adminActionReq struct {
+	Selector
+	Reason string `json:"reason"`
+}
+
+// Handler serves the admin API:
+//
+//	GET  /debug/outbox/events   ?state=&event_type=&aggregate_id=&after_id=&limit=
+//	POST /debug/outbox/redrive  {"state": "dead", "from_id": 1, "to_id": 9, "reason": "..."}
+//	POST /debug/outbox/purge    {"state": "published", "to": "2026-01-01T00:00:00Z", "reason": "..."}
+//
+// Requests must carry token as a bearer token; changes also need an X-Actor
+// header naming the operator, which is audited.
+func (a *Admin) Handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/outbox/events", a.listEvents)
+	mux.HandleFunc("POST /debug/outbox/redrive", a.action(a.Redrive))
+	mux.HandleFunc("POST /debug/outbox/purge", a.action(a.Purge))
+
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			respond.Error(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (a *Admin) listEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := Filter{State: State(q.Get("state")), EventType: q.Get("event_type")}
+	var err error
+	if v := q.Get("aggregate_id"); v != "" {
+		if f.AggregateID, err = uuid.Parse(v); err != nil {
+			respond.Error(w, http.StatusBadRequest, "invalid aggregate_id")
+			return
+		}
+	}
+	if v := q.Get("after_id"); v != "" {
+		if f.AfterID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			respond.Error(w, http.StatusBadRequest, "invalid after_id")
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil {
+			respond.Error(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+
+	events, err := a.List(r.Context(), f)
+	if err != nil {
+		a.fail(w, err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, map[string]any{"events": events})
+}
+
+func (a *Admin) action(do func(ctx context.Context, s Selector, actor, reason string) (int64, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req adminActionReq
+		if err := request.DecodeJSON(w, r, &req); err != nil {
+			respond.Error(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		n, err := do(r.Context(), req.Selector, r.Header.Get("X-Actor"), req.Reason)
+		if err != nil {
+			a.fail(w, err)
+			return
+		}
+		respond.JSON(w, http.StatusOK, map[string]int64{"affected": n})
+	}
+}
+
+func (a *Admin) fail(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrInvalidSelector) {
+		respond.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respond.Error(w, http.StatusInternalServerError, "internal error")
+}
@@ -0,0 +1,75 @@
+package outbox
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSelectorWhere(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	where, args, err := Selector{State: StateDead, FromID: 10, ToID: 20, From: from}.where(StateDead, StatePublished)
+	if err != nil {
+		t.Fatalf("where: %v", err)
+	}
+	if want := "dead_at IS NOT NULL AND id >= $1 AND id <= $2 AND created_at >= $3"; where != want {
+		t.Fatalf("where = %q, want %q", where, want)
+	}
+	if want := []any{int64(10), int64(20), from}; !reflect.DeepEqual(args, want) {
+		t.Fatalf("args = %v, want %v", args, want)
+	}
+
+	for name, s := range map[string]Selector{
+		"pending":   {State: StatePending, ToID: 5},
+		"unknown":   {State: "stuck", ToID: 5},
+		"unbounded": {State: StateDead},
+	} {
+		if _, _, err := s.where(StateDead, StatePublished); !errors.Is(err, ErrInvalidSelector) {
+			t.Fatalf("%s: want ErrInvalidSelector, got %v", name, err)
+		}
+	}
+}
+
+func TestAdminHandlerChecksTokenAndSelector(t *testing.T) {
+	h := NewAdmin(nil, zap.NewNop()).Handler("s3cret")
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/outbox/events", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("without token: %d", rec.Code)
+	}
+
+	for _, tc := range []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/debug/outbox/events?state=stuck", ""},
+		{http.MethodGet, "/debug/outbox/events?aggregate_id=nope", ""},
+		{http.MethodPost, "/debug/outbox/purge", `{"state": "pending", "to_id": 5}`},
+		{http.MethodPost, "/debug/outbox/redrive", `{"state": "dead"}`},
+		{http.MethodPost, "/debug/outbox/redrive", `{"state": "dead", "to_id": 5, "extra": 1}`},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		req.Header.Set("X-Actor", "ops")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s %s %s: got %d, want 400", tc.method, tc.path, tc.body, rec.Code)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/debug/outbox/redrive", strings.NewReader(`{"state": "dead", "to_id": 5}`))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("without actor: got %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,79 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/jackc/pgx/v5"
+)
+
+// Channel is notified by a trigger whenever rows are inserted into the
+// outbox.
+const Channel = "outbox"
+
+const (
+	listenMinBackoff = time.Second
+	listenMaxBackoff = 30 * time.Second
+)
+
+// listen holds a dedicated connection LISTENing on Channel and signals wake
+// for every notification. A lost connection is re-established with backoff;
+// meanwhile the ticker keeps the relay going.
+func (r *Relay) listen(ctx context.Context, wake chan<- struct{}) {
+	backoff := listenMinBackoff
+	for {
+		connected, err := r.listenOnce(ctx, wake)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = listenMinBackoff
+		}
+		r.metrics.reconnects.Inc()
+		r.logger.Warn("outbox listener disconnected, reconnecting", log.Err(err), log.Str("backoff", backoff.String()))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, listenMaxBackoff)
+	}
+}
+
+// listenOnce listens until the connection fails or ctx ends. connected
+// reports whether LISTEN succeeded.
+func (r *Relay) listenOnce(ctx context.Context, wake chan<- struct{}) (connected bool, err error) {
+	conn, err := pgx.ConnectConfig(ctx, r.pool.Config().ConnConfig.Copy())
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{Channel}.Sanitize()); err != nil {
+		return false, err
+	}
+	r.logger.Info("outbox listener connected", log.Str("channel", Channel))
+	// events inserted while we were not listening
+	signal(wake)
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return true, err
+		}
+		signal(wake)
+	}
+}
+
+// signal wakes the relay without blocking; one pending wakeup is enough as
+// a drain picks up everything that is due.
+func signal(wake chan<- struct{}) {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
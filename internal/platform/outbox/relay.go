@@ -3,14 +3,19 @@ package outbox
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/prometheus/client_golang/prometheus"
+	"sync"
 	"time"
 )
 
+// Publisher sends values under one key, in order. A failed call may have
+// sent some of them; the relay publishes at least once.
 type Publisher interface {
-	Publish(ctx context.Context, key string, value []byte) error
+	Publish(ctx context.Context, key string, values ...[]byte) error
 }
 
 type Relay struct {
@@ -20,12 +25,28 @@ type Relay struct {
 	batch   int
 	logger  *log.Logger
 	metrics *relayMetrics
+
+	dlq         Publisher
+	maxAttempts int
+	topic       string
+}
+
+type Option func(*Relay)
+
+// WithDeadLetters moves an event to dlq once it failed maxAttempts times and
+// stops retrying it. topic is recorded in the dead letter as the topic the
+// event was meant for.
+func WithDeadLetters(dlq Publisher, maxAttempts int, topic string) Option {
+	return func(r *Relay) { r.dlq, r.maxAttempts, r.topic = dlq, maxAttempts, topic }
 }
 
 type relayMetrics struct {
 	total  *prometheus.CounterVec
 	errors prometheus.Counter
+	dead   *prometheus.CounterVec
 	lag    prometheus.Gauge
+
+	reconnects prometheus.Counter
 }
 
 func newMetrics() *relayMetrics {
@@ -36,17 +57,23 @@ func newMetrics() *relayMetrics {
 		errors: prometheus.NewCounter(prometheus.CounterOpts{
 			Name: "outbox_publish_errors_total", Help: "outbox publish errors",
 		}),
+		dead: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "outbox_dead_lettered_total", Help: "outbox events moved to the dead letter topic",
+		}, []string{"event"}),
 		lag: prometheus.NewGauge(prometheus.GaugeOpts{
 			Name: "outbox_oldest_age_seconds", Help: "oldest unpublished event age",
 		}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "outbox_listener_reconnects_total", Help: "outbox LISTEN connection losses",
+		}),
 	}
-	prometheus.MustRegister(m.total, m.errors, m.lag)
+	prometheus.MustRegister(m.total, m.errors, m.dead, m.lag, m.reconnects)
 
 	return m
 }
 
-func New(pool *pgxpool.Pool, pub Publisher, interval time.Duration, batch int, logger *log.Logger) *Relay {
-	return &Relay{
+func New(pool *pgxpool.Pool, pub Publisher, interval time.Duration, batch int, logger *log.Logger, opts ...Option) *Relay {
+	r := &Relay{
 		pool:    pool,
 		pub:     pub,
 		ticker:  time.NewTicker(interval),
@@ -54,56 +81,79 @@ func New(pool *pgxpool.Pool, pub Publisher, interval time.Duration, batch int, l
 		logger:  logger,
 		metrics: newMetrics(),
 	}
+	for _, o := range opts {
+		o(r)
+	}
+
+	return r
 }
 
+// Run drains the outbox whenever an insert is notified on the outbox channel
+// and on every tick, which only matters when notifications are lost or
+// events come due after a backoff. A full batch is followed by another
+// drain right away.
 func (r *Relay) Run(ctx context.Context) error {
+	wake := make(chan struct{}, 1)
+	go r.listen(ctx, wake)
+
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case <-r.ticker.C:
-			if err := r.drain(ctx); err != nil {
+		case <-wake:
+		}
+		for ctx.Err() == nil {
+			n, err := r.drain(ctx)
+			if err != nil {
 				r.logger.Error("outbox drain error", log.Err(err))
+				break
+			}
+			if n < r.batch {
+				break
 			}
 		}
 	}
 }
 
-func (r *Relay) drain(ctx context.Context) error {
+// drain publishes up to one batch of due events and returns how many it
+// picked. Events of one aggregate are published in id order: an event is
+// held back while an earlier one of its aggregate is in backoff, fails in
+// this batch or is being published by another relay. Dead events do not
+// block their aggregate.
+func (r *Relay) drain(ctx context.Context) (int, error) {
 	var oldest time.Time
-	_ = r.pool.QueryRow(ctx, `SELECT COALESCE(MIN(created_at), now()) FROM outbox WHERE published_at IS NULL`).Scan(&oldest)
+	_ = r.pool.QueryRow(ctx, `SELECT COALESCE(MIN(created_at), now()) FROM outbox WHERE published_at IS NULL AND dead_at IS NULL`).Scan(&oldest)
 	r.metrics.lag.Set(time.Since(oldest).Seconds())
 
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		r.logger.Error("failed to begin tx", log.Err(err))
-		return err
+		return 0, err
 	}
 	defer func() {
-		if err := tx.Rollback(ctx); err != nil {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
 			r.logger.Error("failed to rollback tx", log.Err(err))
 		}
 	}()
 
 	rows, err := tx.Query(ctx, `
-		SELECT id, event_type, aggregate_type, aggregate_id, payload, created_at
-		FROM outbox
-		WHERE published_at IS NULL AND available_at <= now()
+		SELECT id, event_type, aggregate_type, aggregate_id, payload, created_at, fail_count
+		FROM outbox o
+		WHERE published_at IS NULL AND dead_at IS NULL AND available_at <= now()
+		  AND NOT EXISTS (
+		      SELECT 1 FROM outbox e
+		      WHERE e.aggregate_id = o.aggregate_id AND e.id < o.id
+		        AND e.published_at IS NULL AND e.dead_at IS NULL AND e.available_at > now())
 		ORDER BY id
 		LIMIT $1
 		FOR UPDATE SKIP LOCKED`, r.batch)
 	if err != nil {
 		r.logger.Error("failed to list outbox", log.Err(err))
-		return err
+		return 0, err
 	}
 	defer rows.Close()
 
-	type picked struct {
-		id  int64
-		key string
-		val []byte
-		typ string
-	}
 	var batch []picked
 
 	for rows.Next() {
@@ -114,9 +164,10 @@ func (r *Relay) drain(ctx context.Context) error {
 			aggID        string
 			payloadBytes []byte
 			createdAt    time.Time
+			failCount    int
 		)
-		if err := rows.Scan(&id, &etype, &aggType, &aggID, &payloadBytes, &createdAt); err != nil {
-			return err
+		if err := rows.Scan(&id, &etype, &aggType, &aggID, &payloadBytes, &createdAt, &failCount); err != nil {
+			return 0, err
 		}
 		env, _ := json.Marshal(map[string]any{
 			"type":           etype,
@@ -125,32 +176,205 @@ func (r *Relay) drain(ctx context.Context) error {
 			"payload":        json.RawMessage(payloadBytes),
 			"created_at":     createdAt,
 		})
-		batch = append(batch, picked{id: id, key: aggID, val: env, typ: etype})
+		batch = append(batch, picked{id: id, key: aggID, val: env, typ: etype, attempts: failCount})
 	}
 	if err := rows.Err(); err != nil {
 		r.logger.Error("failed to list outbox", log.Err(err))
-		return err
+		return 0, err
 	}
 	if len(batch) == 0 {
-		return tx.Commit(ctx)
+		return 0, tx.Commit(ctx)
 	}
+	if batch, err = r.inOrder(ctx, tx, batch); err != nil {
+		return 0, err
+	}
+
+	published, failures := r.publish(ctx, batch)
+	for _, f := range failures {
+		r.metrics.errors.Inc()
+		r.failed(ctx, tx, f.first, f.err)
+	}
+	if len(published) > 0 {
+		ids := make([]int64, len(published))
+		for i, m := range published {
+			ids[i] = m.id
+			r.metrics.total.WithLabelValues(m.typ).Inc()
+		}
+		if _, err := tx.Exec(ctx, `UPDATE outbox SET published_at = now() WHERE id = ANY($1)`, ids); err != nil {
+			r.logger.Error("failed to update outbox", log.Err(err))
+			return 0, err
+		}
+	}
+
+	return len(batch), tx.Commit(ctx)
+}
+
+// publishConcurrency bounds the aggregates published at the same time.
+const publishConcurrency = 16
+
+// failure is a key group whose publish failed. Only its first event is
+// charged with the failure; the rest of the group stays pending behind it.
+type failure struct {
+	first picked
+	err   error
+}
+
+// publish sends batch with one Publish call per aggregate, concurrently
+// across aggregates. A group is published or failed as a whole since its
+// events share a key and so a partition.
+func (r *Relay) publish(ctx context.Context, batch []picked) (published []picked, failures []failure) {
+	groups := groupByKey(batch)
+	errs := make([]error, len(groups))
+	sem := make(chan struct{}, publishConcurrency)
+	var wg sync.WaitGroup
+	for i, g := range groups {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			values := make([][]byte, len(g))
+			for j, m := range g {
+				values[j] = m.val
+			}
+			errs[i] = r.pub.Publish(ctx, g[0].key, values...)
+		}()
+	}
+	wg.Wait()
+
+	for i, g := range groups {
+		if errs[i] != nil {
+			failures = append(failures, failure{first: g[0], err: errs[i]})
+			continue
+		}
+		published = append(published, g...)
+	}
+
+	return published, failures
+}
 
+// groupByKey splits batch into its aggregates, keeping the id order within
+// each group and the order in which aggregates first appear.
+func groupByKey(batch []picked) [][]picked {
+	index := map[string]int{}
+	var groups [][]picked
 	for _, m := range batch {
-		if err := r.pub.Publish(ctx, m.key, m.val); err != nil {
-			r.metrics.errors.Inc()
-			_, _ = tx.Exec(ctx, `UPDATE outbox
-				SET fail_count = fail_count + 1,
-				    last_error = $2,
-				    available_at = now() + make_interval(secs => LEAST(60, POW(2, fail_count)))
-				WHERE id = $1`, m.id, err.Error())
+		i, ok := index[m.key]
+		if !ok {
+			i = len(groups)
+			index[m.key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], m)
+	}
+
+	return groups
+}
+
+// inOrder drops the events of batch that must wait for an earlier event of
+// their aggregate which this relay did not pick, e.g. because another relay
+// holds it locked.
+func (r *Relay) inOrder(ctx context.Context, tx pgx.Tx, batch []picked) ([]picked, error) {
+	keys := make([]string, 0, len(batch))
+	seen := map[string]bool{}
+	for _, m := range batch {
+		if !seen[m.key] {
+			seen[m.key] = true
+			keys = append(keys, m.key)
+		}
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT aggregate_id::text, id
+		FROM outbox
+		WHERE aggregate_id = ANY($1::uuid[]) AND id <= $2
+		  AND published_at IS NULL AND dead_at IS NULL
+		ORDER BY id`, keys, batch[len(batch)-1].id)
+	if err != nil {
+		r.logger.Error("failed to list pending outbox", log.Err(err))
+		return nil, err
+	}
+	pending := map[string][]int64{}
+	for rows.Next() {
+		var (
+			key string
+			id  int64
+		)
+		if err := rows.Scan(&key, &id); err != nil {
+			return nil, err
+		}
+		pending[key] = append(pending[key], id)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("failed to list pending outbox", log.Err(err))
+		return nil, err
+	}
+
+	return contiguous(batch, pending), nil
+}
+
+// contiguous keeps, per aggregate, the events of batch that directly follow
+// each other in pending, the ids of the aggregate's unpublished events in
+// order, starting with the first one. batch is ordered by id.
+func contiguous(batch []picked, pending map[string][]int64) []picked {
+	next := map[string]int{}
+	blocked := map[string]bool{}
+	kept := batch[:0:0]
+	for _, m := range batch {
+		if blocked[m.key] {
 			continue
 		}
-		r.metrics.total.WithLabelValues(m.typ).Inc()
-		if _, err := tx.Exec(ctx, `UPDATE outbox SET published_at = now() WHERE id=$1`, m.id); err != nil {
-			r.logger.Error("failed to update outbox", log.Err(err))
-			return err
+		ids, i := pending[m.key], next[m.key]
+		if i >= len(ids) || ids[i] != m.id {
+			blocked[m.key] = true
+			continue
 		}
+		next[m.key] = i + 1
+		kept = append(kept, m)
 	}
 
-	return tx.Commit(ctx)
+	return kept
+}
+
+type picked struct {
+	id  int64
+	key string
+	val []byte
+	typ string
+	// attempts is the number of failed publishes before this one
+	attempts int
+}
+
+// failed records a failed publish of m. Once the event has failed
+// maxAttempts times it is sent to the dead letter topic and marked dead;
+// otherwise, or when the dead letter cannot be sent either, it is retried
+// with exponential backoff.
+func (r *Relay) failed(ctx context.Context, tx pgx.Tx, m picked, pubErr error) {
+	attempts := m.attempts + 1
+	if r.dlq != nil && r.maxAttempts > 0 && attempts >= r.maxAttempts {
+		letter, _ := json.Marshal(map[string]any{
+			"event":          json.RawMessage(m.val),
+			"outbox_id":      m.id,
+			"original_topic": r.topic,
+			"attempts":       attempts,
+			"last_error":     pubErr.Error(),
+			"dead_at":        time.Now().UTC(),
+		})
+		err := r.dlq.Publish(ctx, m.key, letter)
+		if err == nil {
+			if _, err = tx.Exec(ctx, `UPDATE outbox
+				SET fail_count = $2, last_error = $3, dead_at = now()
+				WHERE id = $1`, m.id, attempts, pubErr.Error()); err == nil {
+				r.metrics.dead.WithLabelValues(m.typ).Inc()
+				r.logger.Warn("outbox event dead-lettered", log.Int("id", int(m.id)), log.Str("event", m.typ), log.Int("attempts", attempts), log.Err(pubErr))
+				return
+			}
+		}
+		r.logger.Error("failed to dead-letter outbox event", log.Int("id", int(m.id)), log.Err(err))
+	}
+	if _, err := tx.Exec(ctx, `UPDATE outbox
+		SET fail_count = fail_count + 1,
+		    last_error = $2,
+		    available_at = now() + make_interval(secs => LEAST(60, POW(2, fail_count)))
+		WHERE id = $1`, m.id, pubErr.Error()); err != nil {
+		r.logger.Error("failed to record outbox failure", log.Err(err))
+	}
 }
@@ -0,0 +1,104 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type fakePublisher struct {
+	mu    sync.Mutex
+	calls map[string][][]byte
+	fail  map[string]bool
+	delay time.Duration
+}
+
+func (p *fakePublisher) Publish(_ context.Context, key string, values ...[]byte) error {
+	time.Sleep(p.delay)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail[key] {
+		return errors.New("broker down")
+	}
+	if p.calls == nil {
+		p.calls = map[string][][]byte{}
+	}
+	p.calls[key] = append(p.calls[key], values...)
+
+	return nil
+}
+
+func TestPublishGroupsByKey(t *testing.T) {
+	pub := &fakePublisher{fail: map[string]bool{"b": true}}
+	r := &Relay{pub: pub, logger: zap.NewNop()}
+	batch := []picked{
+		{id: 1, key: "a", val: []byte("a1")},
+		{id: 2, key: "b", val: []byte("b1")},
+		{id: 3, key: "a", val: []byte("a2")},
+		{id: 4, key: "b", val: []byte("b2")},
+	}
+
+	published, failures := r.publish(context.Background(), batch)
+	if len(published) != 2 || published[0].id != 1 || published[1].id != 3 {
+		t.Fatalf("published %+v, want a1, a2", published)
+	}
+	if want := [][]byte{[]byte("a1"), []byte("a2")}; !reflect.DeepEqual(pub.calls["a"], want) {
+		t.Fatalf("a published as %q, want one ordered call", pub.calls["a"])
+	}
+	if len(failures) != 1 || failures[0].first.id != 2 || failures[0].err == nil {
+		t.Fatalf("failures %+v, want b charged to its first event", failures)
+	}
+}
+
+func TestContiguousKeepsAggregateOrder(t *testing.T) {
+	batch := []picked{
+		{id: 1, key: "a"},
+		{id: 2, key: "b"},
+		{id: 4, key: "a"},
+		{id: 5, key: "c"},
+		{id: 6, key: "a"},
+		{id: 7, key: "b"},
+	}
+	pending := map[string][]int64{
+		// 3 is locked by another relay
+		"a": {1, 3, 4, 6},
+		"b": {2, 7},
+		// 0 is not in this batch either
+		"c": {0, 5},
+	}
+
+	var got []int64
+	for _, m := range contiguous(batch, pending) {
+		got = append(got, m.id)
+	}
+	if want := []int64{1, 2, 7}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("kept %v, want %v", got, want)
+	}
+}
+
+// BenchmarkPublish publishes a full batch of 200 events over 50 aggregates
+// to a publisher that takes 1ms per call, standing in for a broker round
+// trip. Publishing event by event would take 200ms per batch.
+func BenchmarkPublish(b *testing.B) {
+	pub := &fakePublisher{delay: time.Millisecond}
+	r := &Relay{pub: pub, logger: zap.NewNop()}
+	batch := make([]picked, 200)
+	for i := range batch {
+		batch[i] = picked{id: int64(i), key: fmt.Sprintf("agg-%d", i%50), val: []byte(`{"type":"order.paid"}`)}
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		pub.calls = nil
+		if _, failures := r.publish(context.Background(), batch); len(failures) > 0 {
+			b.Fatalf("failures: %+v", failures)
+		}
+	}
+	b.ReportMetric(float64(b.N*len(batch))/b.Elapsed().Seconds(), "events/s")
+}
@@ -2,28 +2,59 @@ package saga
 
 import (
 	"context"
-	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"errors"
 	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/jackc/pgx/v5"
 )
 
 type Executor func(ctx context.Context, sagaID string, action string, payload map[string]any) error
 
+// RetryDelay is how long a step that failed with a RetryLaterError waits
+// before it is run again.
+const RetryDelay = time.Minute
+
+// RetryLaterError reports that a step cannot run yet, e.g. because the
+// order it works on is on hold. The step is retried after RetryDelay instead
+// of compensating the saga.
+type RetryLaterError struct {
+	Err error
+}
+
+// RetryLater wraps err in a RetryLaterError.
+func RetryLater(err error) error {
+	return &RetryLaterError{Err: err}
+}
+
+func (e *RetryLaterError) Error() string { return "retry later: " + e.Err.Error() }
+func (e *RetryLaterError) Unwrap() error { return e.Err }
+
 type Manager struct {
-	store  *Store
-	exec   Executor
-	log    *log.Logger
-	ticker *time.Ticker
+	store    *Store
+	exec     Executor
+	handlers map[string]Executor
+	log      *log.Logger
+	ticker   *time.Ticker
 }
 
 func NewManager(store *Store, logger *log.Logger) *Manager {
 	return &Manager{
-		store:  store,
-		exec:   defaultExec(logger),
-		log:    logger,
-		ticker: time.NewTicker(2 * time.Second),
+		store:    store,
+		exec:     defaultExec(logger),
+		handlers: make(map[string]Executor),
+		log:      logger,
+		ticker:   time.NewTicker(2 * time.Second),
 	}
 }
 
+// Handle registers fn for a step action or compensate action. Actions without
+// a handler fall back to the default executor, which only logs. Handle must be
+// called before RunPoller.
+func (m *Manager) Handle(action string, fn Executor) {
+	m.handlers[action] = fn
+}
+
 func (m *Manager) Store() *Store {
 	return m.store
 }
@@ -40,14 +71,42 @@ func (m *Manager) RunPoller(ctx context.Context) error {
 }
 
 func (m *Manager) tick(ctx context.Context) {
+	if m.compensate(ctx) {
+		return
+	}
+	m.advance(ctx)
+}
+
+// advance runs the next pending step. A failed step switches its saga to
+// compensating unless it asked to be retried later.
+func (m *Manager) advance(ctx context.Context) {
 	sagaID, stepNo, _, action, payload, err := m.store.PickNextPending(ctx)
 	if err != nil {
-		m.log.Error("failed to pick next pending", log.Err(err))
+		if !errors.Is(err, pgx.ErrNoRows) {
+			m.log.Error("failed to pick next pending", log.Err(err))
+		}
 		return
 	}
-	if err := m.exec(ctx, sagaID.String(), action, payload); err != nil {
+	if err := m.run(ctx, sagaID.String(), action, payload); err != nil {
+		var rl *RetryLaterError
+		if errors.As(err, &rl) {
+			m.log.Warn("saga step deferred", log.Str("saga_id", sagaID.String()), log.Str("action", action), log.Err(err))
+			if err := m.store.RetryStep(ctx, sagaID, stepNo, err.Error(), time.Now().Add(RetryDelay)); err != nil {
+				m.log.Error("failed to mark step", log.Err(err))
+			}
+			return
+		}
+		m.log.Error("saga step failed", log.Str("saga_id", sagaID.String()), log.Str("action", action), log.Err(err))
 		if err := m.store.MarkStep(ctx, sagaID, stepNo, "failed", err.Error()); err != nil {
 			m.log.Error("failed to mark step", log.Err(err))
+			return
+		}
+		if err := m.store.StartCompensation(ctx, sagaID); err != nil {
+			m.log.Error("failed to start compensation", log.Err(err))
+			return
+		}
+		if err := m.store.TryFinishCompensation(ctx, sagaID); err != nil {
+			m.log.Error("failed to finish compensation", log.Err(err))
 		}
 		return
 	}
@@ -59,6 +118,41 @@ func (m *Manager) tick(ctx context.Context) {
 	}
 }
 
+// compensate undoes the latest completed step of a compensating saga and
+// reports whether there was one.
+func (m *Manager) compensate(ctx context.Context) bool {
+	sagaID, stepNo, _, action, payload, err := m.store.PickNextCompensation(ctx)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			m.log.Error("failed to pick next compensation", log.Err(err))
+		}
+		return false
+	}
+	if err := m.run(ctx, sagaID.String(), action, payload); err != nil {
+		// A failed compensation needs an operator; leave the saga compensating.
+		m.log.Error("saga compensation failed", log.Str("saga_id", sagaID.String()), log.Str("action", action), log.Err(err))
+		if err := m.store.MarkStep(ctx, sagaID, stepNo, "failed", err.Error()); err != nil {
+			m.log.Error("failed to mark step", log.Err(err))
+		}
+		return true
+	}
+	if err := m.store.MarkStep(ctx, sagaID, stepNo, "compensated", ""); err != nil {
+		m.log.Error("failed to mark step", log.Err(err))
+		return true
+	}
+	if err := m.store.TryFinishCompensation(ctx, sagaID); err != nil {
+		m.log.Error("failed to finish compensation", log.Err(err))
+	}
+	return true
+}
+
+func (m *Manager) run(ctx context.Context, sagaID, action string, payload map[string]any) error {
+	if fn, ok := m.handlers[action]; ok {
+		return fn(ctx, sagaID, action, payload)
+	}
+	return m.exec(ctx, sagaID, action, payload)
+}
+
 func defaultExec(logger *log.Logger) Executor {
 	return func(ctx context.Context, sagaID, action string, payload map[string]any) error {
 		logger.Info("saga exec", log.Str("saga_id", sagaID), log.Str("action", action))
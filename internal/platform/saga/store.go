@@ -3,20 +3,44 @@ package saga
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"time"
+
 	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// DefaultStaleAfter is how long a step may stay started before another
+// poller assumes its runner died and picks it again.
+const DefaultStaleAfter = 5 * time.Minute
+
 type Store struct {
-	pool *pgxpool.Pool
-	log  *log.Logger
+	pool       *pgxpool.Pool
+	log        *log.Logger
+	staleAfter time.Duration
+}
+
+type Option func(*Store)
+
+// WithStaleAfter overrides DefaultStaleAfter. Actions must be safe to run
+// again, since a slow step that outlives d is run a second time.
+func WithStaleAfter(d time.Duration) Option {
+	return func(s *Store) { s.staleAfter = d }
 }
 
-func NewStore(p *pgxpool.Pool) *Store {
-	return &Store{
-		pool: p,
+func NewStore(p *pgxpool.Pool, logger *log.Logger, opts ...Option) *Store {
+	s := &Store{
+		pool:       p,
+		log:        logger,
+		staleAfter: DefaultStaleAfter,
+	}
+	for _, o := range opts {
+		o(s)
 	}
+
+	return s
 }
 
 type Step struct {
@@ -30,22 +54,33 @@ type Step struct {
 }
 
 func (s *Store) Create(ctx context.Context, name string, steps []Step, data map[string]any) (uuid.UUID, error) {
-	id := uuid.New()
-	b, err := json.Marshal(data)
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		s.log.Error("failed to marshal data", log.Err(err))
+		s.log.Error("failed to begin tx", log.Err(err))
 		return uuid.Nil, err
 	}
-	tx, err := s.pool.Begin(ctx)
+	defer s.rollback(ctx, tx)
+	id, err := s.CreateInTx(ctx, tx, name, steps, data)
 	if err != nil {
-		s.log.Error("failed to begin tx", log.Err(err))
 		return uuid.Nil, err
 	}
-	defer func() {
-		if err := tx.Rollback(ctx); err != nil {
-			s.log.Error("failed to rollback tx", log.Err(err))
-		}
-	}()
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("failed to commit tx", log.Err(err))
+		return uuid.Nil, err
+	}
+
+	return id, nil
+}
+
+// CreateInTx inserts a pending saga in tx, so it only starts if the change
+// that asked for it commits.
+func (s *Store) CreateInTx(ctx context.Context, tx pgx.Tx, name string, steps []Step, data map[string]any) (uuid.UUID, error) {
+	id := uuid.New()
+	b, err := json.Marshal(data)
+	if err != nil {
+		s.log.Error("failed to marshal data", log.Err(err))
+		return uuid.Nil, err
+	}
 	if _, err := tx.Exec(ctx, `INSERT INTO sagas(id,name,state,data) VALUES ($1,$2,'pending',$3)`, id, name, b); err != nil {
 		s.log.Error("failed to insert saga", log.Err(err))
 		return uuid.Nil, err
@@ -60,18 +95,52 @@ func (s *Store) Create(ctx context.Context, name string, steps []Step, data map[
 			INSERT INTO saga_steps(saga_id, step_no, name, status, action, compensate, payload)
 			VALUES($1,$2,$3,'pending',$4,$5,$6)`,
 			id, st.StepNo, st.Name, st.Action, st.Compensate, sb); err != nil {
+			s.log.Error("failed to insert saga step", log.Err(err))
 			return uuid.Nil, err
 		}
 	}
-	if err := tx.Commit(ctx); err != nil {
-		s.log.Error("failed to commit tx", log.Err(err))
-		return uuid.Nil, err
-	}
 
 	return id, nil
 }
 
+// PickNextPending claims the next forward step of a pending saga. A step is
+// only picked once every earlier step of its saga is done, so steps never run
+// concurrently or out of order. A step left started for longer than the stale
+// timeout, e.g. by a crashed replica, is picked again; one put back by
+// RetryStep waits for its retry time. It returns pgx.ErrNoRows when there is
+// nothing to do.
 func (s *Store) PickNextPending(ctx context.Context) (uuid.UUID, int, string, string, map[string]any, error) {
+	return s.pick(ctx, `
+		SELECT ss.saga_id, ss.step_no, ss.name, ss.action, ss.payload
+		FROM saga_steps ss
+		JOIN sagas s ON s.id = ss.saga_id
+		WHERE s.state = 'pending'
+		  AND ((ss.status='pending' AND (ss.retry_at IS NULL OR ss.retry_at <= now()))
+		    OR (ss.status='started' AND ss.started_at < now() - make_interval(secs => $1)))
+		  AND NOT EXISTS (
+			SELECT 1 FROM saga_steps p
+			WHERE p.saga_id = ss.saga_id AND p.step_no < ss.step_no AND p.status <> 'done'
+		  )
+		ORDER BY s.created_at, ss.step_no
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`, "started", s.staleAfter.Seconds())
+}
+
+// PickNextCompensation claims the latest completed step of a compensating
+// saga and returns its compensate action. Steps are undone in reverse order.
+// It returns pgx.ErrNoRows when there is nothing to do.
+func (s *Store) PickNextCompensation(ctx context.Context) (uuid.UUID, int, string, string, map[string]any, error) {
+	return s.pick(ctx, `
+		SELECT ss.saga_id, ss.step_no, ss.name, ss.compensate, ss.payload
+		FROM saga_steps ss
+		JOIN sagas s ON s.id = ss.saga_id
+		WHERE ss.status='done' AND COALESCE(ss.compensate, '') <> '' AND s.state = 'compensating'
+		ORDER BY s.created_at, ss.step_no DESC
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`, "compensating")
+}
+
+func (s *Store) pick(ctx context.Context, query, claimStatus string, args ...any) (uuid.UUID, int, string, string, map[string]any, error) {
 	var (
 		sagaID uuid.UUID
 		stepNo int
@@ -84,25 +153,15 @@ func (s *Store) PickNextPending(ctx context.Context) (uuid.UUID, int, string, st
 		s.log.Error("failed to begin tx", log.Err(err))
 		return uuid.Nil, 0, "", "", nil, err
 	}
-	defer func() {
-		if err := tx.Rollback(ctx); err != nil {
-			s.log.Error("failed to rollback tx", log.Err(err))
-		}
-	}()
+	defer s.rollback(ctx, tx)
 
-	row := tx.QueryRow(ctx, `
-		SELECT ss.saga_id, ss.step_no, ss.name, ss.action, ss.payload
-		FROM saga_steps ss
-		JOIN sagas s ON s.id = ss.saga_id
-		WHERE ss.status='pending' AND s.state IN ('pending','compensating')
-		ORDER BY s.created_at, ss.step_no
-		LIMIT 1
-		FOR UPDATE SKIP LOCKED`)
-	if err := row.Scan(&sagaID, &stepNo, &name, &action, &pl); err != nil {
-		s.log.Error("failed to pick next pending", log.Err(err))
+	if err := tx.QueryRow(ctx, query, args...).Scan(&sagaID, &stepNo, &name, &action, &pl); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			s.log.Error("failed to pick saga step", log.Err(err))
+		}
 		return uuid.Nil, 0, "", "", nil, err
 	}
-	if _, err := tx.Exec(ctx, `UPDATE saga_steps SET status='started', started_at=now() WHERE saga_id=$1 AND step_no=$2`, sagaID, stepNo); err != nil {
+	if _, err := tx.Exec(ctx, `UPDATE saga_steps SET status=$3, started_at=now() WHERE saga_id=$1 AND step_no=$2`, sagaID, stepNo, claimStatus); err != nil {
 		s.log.Error("failed to update step", log.Err(err))
 		return uuid.Nil, 0, "", "", nil, err
 	}
@@ -120,7 +179,18 @@ func (s *Store) PickNextPending(ctx context.Context) (uuid.UUID, int, string, st
 
 func (s *Store) MarkStep(ctx context.Context, sagaID uuid.UUID, stepNo int, status string, errText string) error {
 	_, err := s.pool.Exec(ctx, `UPDATE saga_steps SET status=$3, error=$4, finished_at=now() WHERE saga_id=$1 AND step_no=$2`,
-		sagaID, stepNo, nullIfEmpty(errText))
+		sagaID, stepNo, status, nullIfEmpty(errText))
+	if err != nil {
+		s.log.Error("failed to update step", log.Err(err))
+		return err
+	}
+	return nil
+}
+
+// RetryStep puts a started step back to pending, to be picked again from at.
+func (s *Store) RetryStep(ctx context.Context, sagaID uuid.UUID, stepNo int, errText string, at time.Time) error {
+	_, err := s.pool.Exec(ctx, `UPDATE saga_steps SET status='pending', error=$3, retry_at=$4 WHERE saga_id=$1 AND step_no=$2`,
+		sagaID, stepNo, nullIfEmpty(errText), at)
 	if err != nil {
 		s.log.Error("failed to update step", log.Err(err))
 		return err
@@ -135,7 +205,7 @@ func (s *Store) TryCompleteSaga(ctx context.Context, sagaID uuid.UUID) error {
 		return err
 	}
 	if pending == 0 {
-		_, err := s.pool.Exec(ctx, `UPDATE sagas SET state='completed', updated_at=now() WHERE id=$1`, sagaID)
+		_, err := s.pool.Exec(ctx, `UPDATE sagas SET state='completed', updated_at=now() WHERE id=$1 AND state='pending'`, sagaID)
 		if err != nil {
 			s.log.Error("failed to update saga", log.Err(err))
 			return err
@@ -145,6 +215,55 @@ func (s *Store) TryCompleteSaga(ctx context.Context, sagaID uuid.UUID) error {
 	return nil
 }
 
+// StartCompensation switches a saga whose step failed to compensating. Steps
+// that never ran are marked skipped; completed ones get undone by the poller.
+func (s *Store) StartCompensation(ctx context.Context, sagaID uuid.UUID) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		s.log.Error("failed to begin tx", log.Err(err))
+		return err
+	}
+	defer s.rollback(ctx, tx)
+
+	if _, err := tx.Exec(ctx, `UPDATE sagas SET state='compensating', updated_at=now() WHERE id=$1 AND state='pending'`, sagaID); err != nil {
+		s.log.Error("failed to update saga", log.Err(err))
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE saga_steps SET status='skipped' WHERE saga_id=$1 AND status='pending'`, sagaID); err != nil {
+		s.log.Error("failed to skip steps", log.Err(err))
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		s.log.Error("failed to commit tx", log.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// TryFinishCompensation marks a compensating saga failed once nothing is left
+// to undo.
+func (s *Store) TryFinishCompensation(ctx context.Context, sagaID uuid.UUID) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE sagas SET state='failed', updated_at=now()
+		WHERE id=$1 AND state='compensating' AND NOT EXISTS (
+			SELECT 1 FROM saga_steps
+			WHERE saga_id=$1 AND (status='compensating' OR (status='done' AND COALESCE(compensate, '') <> ''))
+		)`, sagaID)
+	if err != nil {
+		s.log.Error("failed to update saga", log.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) rollback(ctx context.Context, tx pgx.Tx) {
+	if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		s.log.Error("failed to rollback tx", log.Err(err))
+	}
+}
+
 func nullIfEmpty(s string) any {
 	if s == "" {
 		return nil
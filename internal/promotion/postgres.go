@@ -0,0 +1,118 @@
+package promotion
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrLimitReached is returned by RedeemInTx when a code has been used up,
+// globally or by the customer.
+var ErrLimitReached = errors.New("usage limit reached")
+
+// Store reads promotions from the promotions table and counts redemptions.
+type Store struct {
+	pool *pgxpool.Pool
+	log  *log.Logger
+}
+
+func NewStore(pool *pgxpool.Pool, logger *log.Logger) *Store {
+	return &Store{pool: pool, log: logger}
+}
+
+// Lookup returns the active promotions with the given codes. Unknown codes
+// are left out.
+func (s *Store) Lookup(ctx context.Context, codes []string) ([]Promotion, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT code, kind, percent_off, amount_minor, COALESCE(currency, ''), COALESCE(sku, ''),
+		       buy_quantity, get_quantity, starts_at, ends_at, max_uses, max_uses_per_customer, stackable
+		FROM promotions
+		WHERE code = ANY($1) AND active`, codes)
+	if err != nil {
+		s.log.Error("failed to query promotions", log.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var promos []Promotion
+	for rows.Next() {
+		var (
+			p            Promotion
+			starts, ends *time.Time
+		)
+		if err := rows.Scan(&p.Code, &p.Kind, &p.PercentOff, &p.AmountMinor, &p.Currency, &p.SKU,
+			&p.BuyQuantity, &p.GetQuantity, &starts, &ends, &p.MaxUses, &p.MaxUsesPerCustomer, &p.Stackable); err != nil {
+			s.log.Error("failed to scan promotion", log.Err(err))
+			return nil, err
+		}
+		if starts != nil {
+			p.StartsAt = *starts
+		}
+		if ends != nil {
+			p.EndsAt = *ends
+		}
+		promos = append(promos, p)
+	}
+	if err := rows.Err(); err != nil {
+		s.log.Error("failed to query promotions", log.Err(err))
+		return nil, err
+	}
+
+	return promos, nil
+}
+
+// RedeemInTx counts one use of p by customerID for orderID. The counters are
+// row-locked by the increments, so concurrent orders cannot both take the
+// last use; on ErrLimitReached the caller must roll tx back.
+func (s *Store) RedeemInTx(ctx context.Context, tx pgx.Tx, p Promotion, customerID, orderID uuid.UUID, amount int64) error {
+	var uses int
+	if err := tx.QueryRow(ctx, `
+		UPDATE promotions SET uses = uses + 1
+		WHERE code=$1
+		RETURNING uses`, p.Code).Scan(&uses); err != nil {
+		s.log.Error("failed to count promotion use", log.Err(err))
+		return err
+	}
+	if p.MaxUses > 0 && uses > p.MaxUses {
+		return ErrLimitReached
+	}
+
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO promotion_customer_uses (code, customer_id, uses)
+		VALUES ($1,$2,1)
+		ON CONFLICT (code, customer_id) DO UPDATE SET uses = promotion_customer_uses.uses + 1
+		RETURNING uses`, p.Code, customerID).Scan(&uses); err != nil {
+		s.log.Error("failed to count customer promotion use", log.Err(err))
+		return err
+	}
+	if p.MaxUsesPerCustomer > 0 && uses > p.MaxUsesPerCustomer {
+		return ErrLimitReached
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO promotion_redemptions (code, order_id, customer_id, discount_minor)
+		VALUES ($1,$2,$3,$4)`, p.Code, orderID, customerID, amount); err != nil {
+		s.log.Error("failed to insert promotion redemption", log.Err(err))
+		return err
+	}
+
+	return nil
+}
+
+// UpdateRedemptionsInTx sets the discount recorded for each code redeemed on
+// orderID to its amount, or to zero when the code is not in amounts.
+func (s *Store) UpdateRedemptionsInTx(ctx context.Context, tx pgx.Tx, orderID uuid.UUID, amounts map[string]int64) error {
+	if _, err := tx.Exec(ctx, `
+		UPDATE promotion_redemptions
+		SET discount_minor = COALESCE(($2::jsonb ->> code)::bigint, 0)
+		WHERE order_id = $1`, orderID, amounts); err != nil {
+		s.log.Error("failed to update promotion redemptions", log.Err(err))
+		return err
+	}
+	return nil
+}
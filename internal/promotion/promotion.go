@@ -0,0 +1,175 @@
+// Package promotion evaluates discount codes against an order.
+package promotion
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+)
+
+type Kind string
+
+const (
+	// KindPercentage takes PercentOff percent off the order.
+	KindPercentage Kind = "percentage"
+	// KindFixedAmount takes AmountMinor off the order; Currency must match.
+	KindFixedAmount Kind = "fixed_amount"
+	// KindBuyXGetY makes GetQuantity of every BuyQuantity+GetQuantity units of
+	// SKU (or of any single line when SKU is empty) free.
+	KindBuyXGetY Kind = "buy_x_get_y"
+	// KindFreeShipping waives shipping; it does not change the order total.
+	KindFreeShipping Kind = "free_shipping"
+)
+
+// Promotion is a discount code and its rules.
+type Promotion struct {
+	Code        string
+	Kind        Kind
+	PercentOff  int
+	AmountMinor int64
+	Currency    string
+	SKU         string
+	BuyQuantity int
+	GetQuantity int
+	// StartsAt and EndsAt bound the validity window; zero means open.
+	StartsAt time.Time
+	EndsAt   time.Time
+	// MaxUses and MaxUsesPerCustomer limit redemptions; zero means unlimited.
+	MaxUses            int
+	MaxUsesPerCustomer int
+	// Stackable promotions can be combined with other stackable ones.
+	Stackable bool
+}
+
+// Line is an order line as seen by the engine.
+type Line struct {
+	SKU        string
+	Quantity   int
+	PriceMinor int64
+}
+
+// Discount is the effect of one promotion on an order.
+type Discount struct {
+	Code         string
+	Kind         Kind
+	AmountMinor  int64
+	FreeShipping bool
+}
+
+// RejectedError reports a code that cannot be applied to the order.
+type RejectedError struct {
+	Code   string
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("promotion %s: %s", e.Code, e.Reason)
+}
+
+// Apply evaluates promos against the lines of an order in currency at now.
+// Line discounts (buy X get Y) are taken first, then percentages and then
+// fixed amounts, each from what is left, so the order total never goes below
+// zero. Amounts are computed with checked Money arithmetic, so a total that
+// does not fit in int64 yields domain.ErrAmountOverflow.
+func Apply(promos []Promotion, currency string, lines []Line, now time.Time) ([]Discount, error) {
+	if err := checkStacking(promos); err != nil {
+		return nil, err
+	}
+	total := domain.NewMoney(0, currency)
+	for _, l := range lines {
+		lt, err := domain.NewMoney(l.PriceMinor, currency).Mul(int64(l.Quantity))
+		if err != nil {
+			return nil, err
+		}
+		if total, err = total.Add(lt); err != nil {
+			return nil, err
+		}
+	}
+	remaining := total.Amount
+
+	for _, p := range promos {
+		switch p.Kind {
+		case KindBuyXGetY, KindPercentage, KindFixedAmount, KindFreeShipping:
+		default:
+			return nil, &RejectedError{Code: p.Code, Reason: "unsupported kind " + string(p.Kind)}
+		}
+		if !p.StartsAt.IsZero() && now.Before(p.StartsAt) {
+			return nil, &RejectedError{Code: p.Code, Reason: "not yet valid"}
+		}
+		if !p.EndsAt.IsZero() && !now.Before(p.EndsAt) {
+			return nil, &RejectedError{Code: p.Code, Reason: "expired"}
+		}
+	}
+
+	out := make([]Discount, 0, len(promos))
+	for _, kind := range []Kind{KindBuyXGetY, KindPercentage, KindFixedAmount, KindFreeShipping} {
+		for _, p := range promos {
+			if p.Kind != kind {
+				continue
+			}
+			d := Discount{Code: p.Code, Kind: p.Kind}
+			switch p.Kind {
+			case KindBuyXGetY:
+				if p.BuyQuantity <= 0 || p.GetQuantity <= 0 {
+					return nil, &RejectedError{Code: p.Code, Reason: "misconfigured"}
+				}
+				off := domain.NewMoney(0, currency)
+				for _, l := range lines {
+					if p.SKU != "" && l.SKU != p.SKU {
+						continue
+					}
+					free := l.Quantity / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+					lo, err := domain.NewMoney(l.PriceMinor, currency).Mul(int64(free))
+					if err != nil {
+						return nil, err
+					}
+					if off, err = off.Add(lo); err != nil {
+						return nil, err
+					}
+				}
+				d.AmountMinor = off.Amount
+				if d.AmountMinor == 0 {
+					return nil, &RejectedError{Code: p.Code, Reason: "no qualifying items"}
+				}
+			case KindPercentage:
+				if p.PercentOff <= 0 || p.PercentOff > 100 {
+					return nil, &RejectedError{Code: p.Code, Reason: "misconfigured"}
+				}
+				// Allocate splits remaining exactly without multiplying
+				// it in int64; the first part is the discount, rounded to the
+				// nearest minor unit.
+				parts, err := domain.NewMoney(remaining, currency).Allocate([]int64{int64(p.PercentOff), int64(100 - p.PercentOff)})
+				if err != nil {
+					return nil, err
+				}
+				d.AmountMinor = parts[0].Amount
+			case KindFixedAmount:
+				if p.Currency != currency {
+					return nil, &RejectedError{Code: p.Code, Reason: "not valid in " + currency}
+				}
+				d.AmountMinor = p.AmountMinor
+			case KindFreeShipping:
+				d.FreeShipping = true
+			}
+			d.AmountMinor = min(d.AmountMinor, remaining)
+			remaining -= d.AmountMinor
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func checkStacking(promos []Promotion) error {
+	seen := make(map[string]bool, len(promos))
+	for _, p := range promos {
+		if seen[p.Code] {
+			return &RejectedError{Code: p.Code, Reason: "used more than once"}
+		}
+		seen[p.Code] = true
+		if len(promos) > 1 && !p.Stackable {
+			return &RejectedError{Code: p.Code, Reason: "cannot be combined with other codes"}
+		}
+	}
+	return nil
+}
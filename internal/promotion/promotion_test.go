@@ -0,0 +1,102 @@
+package promotion
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/order/domain"
+)
+
+func TestApply(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	lines := []Line{
+		{SKU: "A", Quantity: 3, PriceMinor: 1000}, // 3000
+		{SKU: "B", Quantity: 1, PriceMinor: 500},  // 500
+	}
+
+	for _, tc := range []struct {
+		name   string
+		promos []Promotion
+		want   []int64
+		reject bool
+	}{
+		{name: "percentage", promos: []Promotion{{Code: "P10", Kind: KindPercentage, PercentOff: 10}}, want: []int64{350}},
+		{name: "fixed", promos: []Promotion{{Code: "F5", Kind: KindFixedAmount, AmountMinor: 500, Currency: "USD"}}, want: []int64{500}},
+		{name: "fixed capped at total", promos: []Promotion{{Code: "F", Kind: KindFixedAmount, AmountMinor: 9999, Currency: "USD"}}, want: []int64{3500}},
+		{name: "fixed wrong currency", promos: []Promotion{{Code: "F", Kind: KindFixedAmount, AmountMinor: 500, Currency: "EUR"}}, reject: true},
+		{name: "buy 2 get 1", promos: []Promotion{{Code: "B2G1", Kind: KindBuyXGetY, SKU: "A", BuyQuantity: 2, GetQuantity: 1}}, want: []int64{1000}},
+		{name: "buy x get y without qualifying items", promos: []Promotion{{Code: "B", Kind: KindBuyXGetY, SKU: "B", BuyQuantity: 1, GetQuantity: 1}}, reject: true},
+		{name: "free shipping", promos: []Promotion{{Code: "SHIP", Kind: KindFreeShipping}}, want: []int64{0}},
+		{
+			name: "stacked: line discount, then percentage, then fixed",
+			promos: []Promotion{
+				{Code: "F", Kind: KindFixedAmount, AmountMinor: 100, Currency: "USD", Stackable: true},
+				{Code: "P", Kind: KindPercentage, PercentOff: 10, Stackable: true},
+				{Code: "B", Kind: KindBuyXGetY, SKU: "A", BuyQuantity: 2, GetQuantity: 1, Stackable: true},
+			},
+			want: []int64{1000, 250, 100},
+		},
+		{
+			name: "not stackable",
+			promos: []Promotion{
+				{Code: "P", Kind: KindPercentage, PercentOff: 10},
+				{Code: "S", Kind: KindFreeShipping, Stackable: true},
+			},
+			reject: true,
+		},
+		{name: "duplicate", promos: []Promotion{{Code: "S", Kind: KindFreeShipping, Stackable: true}, {Code: "S", Kind: KindFreeShipping, Stackable: true}}, reject: true},
+		{name: "not started", promos: []Promotion{{Code: "P", Kind: KindPercentage, PercentOff: 10, StartsAt: now.Add(time.Hour)}}, reject: true},
+		{name: "expired", promos: []Promotion{{Code: "P", Kind: KindPercentage, PercentOff: 10, EndsAt: now}}, reject: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Apply(tc.promos, "USD", lines, now)
+			if tc.reject {
+				var re *RejectedError
+				if !errors.As(err, &re) {
+					t.Fatalf("want RejectedError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d discounts, want %d", len(got), len(tc.want))
+			}
+			for i, d := range got {
+				if d.AmountMinor != tc.want[i] {
+					t.Errorf("discount %d (%s): got %d want %d", i, d.Code, d.AmountMinor, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyLargeAmounts(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	// remaining * PercentOff would overflow int64 here.
+	big := []Line{{SKU: "A", Quantity: 1, PriceMinor: math.MaxInt64 / 2}}
+	got, err := Apply([]Promotion{{Code: "P", Kind: KindPercentage, PercentOff: 50}}, "USD", big, now)
+	if err != nil {
+		t.Fatalf("percentage: %v", err)
+	}
+	if want := int64(math.MaxInt64 / 4); got[0].AmountMinor != want && got[0].AmountMinor != want+1 {
+		t.Fatalf("percentage: got %d want about %d", got[0].AmountMinor, want)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		promos []Promotion
+		lines  []Line
+	}{
+		{name: "line total", promos: []Promotion{{Code: "S", Kind: KindFreeShipping}}, lines: []Line{{SKU: "A", Quantity: 3, PriceMinor: math.MaxInt64 / 2}}},
+		{name: "order total", promos: []Promotion{{Code: "S", Kind: KindFreeShipping}}, lines: []Line{{SKU: "A", Quantity: 1, PriceMinor: math.MaxInt64}, {SKU: "B", Quantity: 1, PriceMinor: 1}}},
+	} {
+		if _, err := Apply(tc.promos, "USD", tc.lines, now); !errors.Is(err, domain.ErrAmountOverflow) {
+			t.Errorf("%s: want ErrAmountOverflow, got %v", tc.name, err)
+		}
+	}
+}
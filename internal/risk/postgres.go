@@ -0,0 +1,46 @@
+package risk
+
+import (
+	"context"
+	"time"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store counts orders for the velocity rules and keeps an audit trail of
+// assessments in the risk_assessments table.
+type Store struct {
+	pool *pgxpool.Pool
+	log  *log.Logger
+}
+
+func NewStore(pool *pgxpool.Pool, logger *log.Logger) *Store {
+	return &Store{pool: pool, log: logger}
+}
+
+func (s *Store) CountOrders(ctx context.Context, customerID uuid.UUID, since time.Time) (int, error) {
+	var n int
+	if err := s.pool.QueryRow(ctx, `
+		SELECT count(*) FROM orders
+		WHERE customer_id=$1 AND created_at >= $2`, customerID, since).Scan(&n); err != nil {
+		s.log.Error("failed to count customer orders", log.Err(err))
+		return 0, err
+	}
+	return n, nil
+}
+
+// SaveInTx records the assessment of an order. Rejected orders are never
+// stored, so order_id is not a foreign key.
+func (s *Store) SaveInTx(ctx context.Context, tx pgx.Tx, orderID, customerID uuid.UUID, a Assessment) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO risk_assessments (order_id, customer_id, score, decision, factors)
+		VALUES ($1,$2,$3,$4,$5)`,
+		orderID, customerID, a.Score, a.Decision, a.Factors); err != nil {
+		s.log.Error("failed to save risk assessment", log.Err(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+// Package risk scores new orders against configurable fraud rules.
+package risk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Decision is what happens to an order after scoring.
+type Decision string
+
+const (
+	Accept Decision = "accept"
+	Hold   Decision = "hold"
+	Reject Decision = "reject"
+)
+
+type Kind string
+
+const (
+	// KindVelocity scores customers that placed more than MaxOrders orders
+	// within Window.
+	KindVelocity Kind = "velocity"
+	// KindHighTotal scores orders whose total reaches AmountMinor.
+	KindHighTotal Kind = "high_total"
+	// KindCountryMismatch scores orders whose billing and shipping country
+	// differ.
+	KindCountryMismatch Kind = "country_mismatch"
+	// KindNewCustomerHighValue scores orders reaching AmountMinor from
+	// customers with at most MaxPriorOrders earlier orders.
+	KindNewCustomerHighValue Kind = "new_customer_high_value"
+)
+
+// Rule adds Score to an order it matches. Amount thresholds are in the minor
+// unit of Currency and only apply to orders whose total or reporting total is
+// in that currency.
+type Rule struct {
+	Kind           Kind     `json:"kind"`
+	Score          int      `json:"score"`
+	Window         Duration `json:"window,omitempty"`
+	MaxOrders      int      `json:"max_orders,omitempty"`
+	AmountMinor    int64    `json:"amount_minor,omitempty"`
+	Currency       string   `json:"currency,omitempty"`
+	MaxPriorOrders int      `json:"max_prior_orders,omitempty"`
+}
+
+// Config is a rule set and the scores at which orders are held or rejected;
+// a zero threshold never triggers.
+type Config struct {
+	HoldScore   int    `json:"hold_score"`
+	RejectScore int    `json:"reject_score"`
+	Rules       []Rule `json:"rules"`
+}
+
+// Duration is a time.Duration written as "1h30m" in config files.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadConfig reads a JSON rule set from path.
+func LoadConfig(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks that every rule is complete.
+func (c Config) Validate() error {
+	if c.HoldScore < 0 || c.RejectScore < 0 {
+		return fmt.Errorf("thresholds must not be negative")
+	}
+	if c.HoldScore > 0 && c.RejectScore > 0 && c.HoldScore > c.RejectScore {
+		return fmt.Errorf("hold_score %d is above reject_score %d", c.HoldScore, c.RejectScore)
+	}
+	for i, r := range c.Rules {
+		var err error
+		switch r.Kind {
+		case KindVelocity:
+			if r.Window <= 0 || r.MaxOrders <= 0 {
+				err = fmt.Errorf("needs window and max_orders")
+			}
+		case KindHighTotal, KindNewCustomerHighValue:
+			if r.AmountMinor <= 0 || r.Currency == "" {
+				err = fmt.Errorf("needs amount_minor and currency")
+			}
+		case KindCountryMismatch:
+		default:
+			err = fmt.Errorf("unknown kind %q", r.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("rule %d (%s): %w", i, r.Kind, err)
+		}
+	}
+	return nil
+}
+
+// Amount is an amount in minor units of Currency.
+type Amount struct {
+	Minor    int64
+	Currency string
+}
+
+// Order is an order as seen by the engine.
+type Order struct {
+	ID              uuid.UUID
+	CustomerID      uuid.UUID
+	Total           Amount
+	ReportingTotal  *Amount
+	ShippingCountry string
+	BillingCountry  string
+	// Stored is set when o is already saved, e.g. when it is scored again
+	// after a change, so History counts it.
+	Stored bool
+}
+
+// total returns the order total in currency, if known.
+func (o Order) total(currency string) (int64, bool) {
+	if o.Total.Currency == currency {
+		return o.Total.Minor, true
+	}
+	if o.ReportingTotal != nil && o.ReportingTotal.Currency == currency {
+		return o.ReportingTotal.Minor, true
+	}
+	return 0, false
+}
+
+// History counts earlier orders of a customer.
+type History interface {
+	// CountOrders returns how many orders customerID placed at or after
+	// since.
+	CountOrders(ctx context.Context, customerID uuid.UUID, since time.Time) (int, error)
+}
+
+// Factor is the contribution of one matched rule.
+type Factor struct {
+	Rule   Kind   `json:"rule"`
+	Score  int    `json:"score"`
+	Detail string `json:"detail"`
+}
+
+// Assessment is the outcome of scoring an order.
+type Assessment struct {
+	Score    int      `json:"score"`
+	Decision Decision `json:"decision"`
+	Factors  []Factor `json:"factors"`
+}
+
+// Summary lists the matched rules, for hold notes and logs.
+func (a Assessment) Summary() string {
+	parts := make([]string, len(a.Factors))
+	for i, f := range a.Factors {
+		parts[i] = f.Detail
+	}
+	return fmt.Sprintf("risk score %d: %s", a.Score, strings.Join(parts, "; "))
+}
+
+type Engine struct {
+	cfg  Config
+	hist History
+	now  func() time.Time
+}
+
+func NewEngine(cfg Config, hist History) (*Engine, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Engine{cfg: cfg, hist: hist, now: time.Now}, nil
+}
+
+// Assess scores o against every rule and decides on the sum. Reject wins
+// over hold when both thresholds are reached.
+func (e *Engine) Assess(ctx context.Context, o Order) (Assessment, error) {
+	a := Assessment{Decision: Accept, Factors: []Factor{}}
+	for _, r := range e.cfg.Rules {
+		detail, ok, err := e.match(ctx, r, o)
+		if err != nil {
+			return Assessment{}, err
+		}
+		if ok {
+			a.Score += r.Score
+			a.Factors = append(a.Factors, Factor{Rule: r.Kind, Score: r.Score, Detail: detail})
+		}
+	}
+	switch {
+	case e.cfg.RejectScore > 0 && a.Score >= e.cfg.RejectScore:
+		a.Decision = Reject
+	case e.cfg.HoldScore > 0 && a.Score >= e.cfg.HoldScore:
+		a.Decision = Hold
+	}
+	return a, nil
+}
+
+func (e *Engine) match(ctx context.Context, r Rule, o Order) (string, bool, error) {
+	switch r.Kind {
+	case KindVelocity:
+		n, err := e.hist.CountOrders(ctx, o.CustomerID, e.now().Add(-time.Duration(r.Window)))
+		if err != nil {
+			return "", false, err
+		}
+		if !o.Stored {
+			n++
+		}
+		return fmt.Sprintf("%d orders within %s", n, time.Duration(r.Window)), n > r.MaxOrders, nil
+	case KindHighTotal:
+		total, ok := o.total(r.Currency)
+		return fmt.Sprintf("total %d %s", total, r.Currency), ok && total >= r.AmountMinor, nil
+	case KindCountryMismatch:
+		ok := o.ShippingCountry != "" && o.BillingCountry != "" && !strings.EqualFold(o.ShippingCountry, o.BillingCountry)
+		return fmt.Sprintf("shipping to %s, billing in %s", o.ShippingCountry, o.BillingCountry), ok, nil
+	case KindNewCustomerHighValue:
+		total, ok := o.total(r.Currency)
+		if !ok || total < r.AmountMinor {
+			return "", false, nil
+		}
+		n, err := e.hist.CountOrders(ctx, o.CustomerID, time.Time{})
+		if err != nil {
+			return "", false, err
+		}
+		if o.Stored {
+			n--
+		}
+		return fmt.Sprintf("%d prior orders, total %d %s", n, total, r.Currency), n <= r.MaxPriorOrders, nil
+	}
+	return "", false, nil
+}
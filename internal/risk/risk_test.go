@@ -0,0 +1,121 @@
+package risk
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeHistory counts orders placed at the given times.
+type fakeHistory []time.Time
+
+func (f fakeHistory) CountOrders(_ context.Context, _ uuid.UUID, since time.Time) (int, error) {
+	n := 0
+	for _, at := range f {
+		if !at.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestAssess(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cfg := Config{
+		HoldScore:   50,
+		RejectScore: 100,
+		Rules: []Rule{
+			{Kind: KindVelocity, Score: 40, Window: Duration(time.Hour), MaxOrders: 2},
+			{Kind: KindHighTotal, Score: 30, AmountMinor: 100000, Currency: "USD"},
+			{Kind: KindCountryMismatch, Score: 20},
+			{Kind: KindNewCustomerHighValue, Score: 40, AmountMinor: 50000, Currency: "USD"},
+		},
+	}
+	usd := func(minor int64) Amount { return Amount{Minor: minor, Currency: "USD"} }
+	old := now.Add(-48 * time.Hour)
+	recent := now.Add(-10 * time.Minute)
+
+	for _, tc := range []struct {
+		name     string
+		hist     fakeHistory
+		order    Order
+		score    int
+		decision Decision
+	}{
+		{name: "regular customer", hist: fakeHistory{old}, order: Order{Total: usd(2000), ShippingCountry: "US", BillingCountry: "US"}, decision: Accept},
+		{name: "country mismatch only", hist: fakeHistory{old}, order: Order{Total: usd(2000), ShippingCountry: "US", BillingCountry: "ng"}, score: 20, decision: Accept},
+		{name: "velocity and mismatch", hist: fakeHistory{old, recent, recent}, order: Order{Total: usd(2000), ShippingCountry: "US", BillingCountry: "DE"}, score: 60, decision: Hold},
+		{name: "new customer, high value", order: Order{Total: usd(150000)}, score: 70, decision: Hold},
+		{name: "thresholds in reporting currency", order: Order{Total: Amount{Minor: 20000000, Currency: "JPY"}, ReportingTotal: &Amount{Minor: 130000, Currency: "USD"}, ShippingCountry: "JP", BillingCountry: "US"}, score: 90, decision: Hold},
+		{name: "everything", hist: fakeHistory{recent, recent}, order: Order{Total: usd(150000), ShippingCountry: "US", BillingCountry: "DE"}, score: 90, decision: Hold},
+		{name: "stored order is not counted twice", hist: fakeHistory{old, recent, recent}, order: Order{Total: usd(150000), ShippingCountry: "US", BillingCountry: "US", Stored: true}, score: 30, decision: Accept},
+		{name: "no amount in rule currency", order: Order{Total: Amount{Minor: 20000000, Currency: "JPY"}}, decision: Accept},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := NewEngine(cfg, tc.hist)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.now = func() time.Time { return now }
+			a, err := e.Assess(context.Background(), tc.order)
+			if err != nil {
+				t.Fatalf("assess: %v", err)
+			}
+			if a.Score != tc.score || a.Decision != tc.decision {
+				t.Fatalf("got score %d %s, want %d %s (%+v)", a.Score, a.Decision, tc.score, tc.decision, a.Factors)
+			}
+			sum := 0
+			for _, f := range a.Factors {
+				sum += f.Score
+			}
+			if sum != a.Score {
+				t.Fatalf("factors do not add up to the score: %+v", a.Factors)
+			}
+		})
+	}
+
+	cfg.RejectScore = 90
+	e, _ := NewEngine(cfg, fakeHistory{recent, recent})
+	e.now = func() time.Time { return now }
+	if a, _ := e.Assess(context.Background(), Order{Total: usd(150000), ShippingCountry: "US", BillingCountry: "DE"}); a.Decision != Reject {
+		t.Fatalf("reject should win over hold, got %s", a.Decision)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	cfg, err := LoadConfig(write("ok.json", `{"hold_score": 10, "rules": [{"kind": "velocity", "score": 10, "window": "30m", "max_orders": 3}]}`))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(cfg.Rules) != 1 || time.Duration(cfg.Rules[0].Window) != 30*time.Minute {
+		t.Fatalf("loaded %+v", cfg)
+	}
+
+	for name, body := range map[string]string{
+		"unknown kind":      `{"rules": [{"kind": "zodiac", "score": 1}]}`,
+		"unknown field":     `{"rules": [], "holdscore": 1}`,
+		"bad duration":      `{"rules": [{"kind": "velocity", "score": 1, "window": "soon", "max_orders": 1}]}`,
+		"missing threshold": `{"rules": [{"kind": "high_total", "score": 1}]}`,
+		"hold above reject": `{"hold_score": 20, "reject_score": 10, "rules": []}`,
+	} {
+		if _, err := LoadConfig(write("bad.json", body)); err == nil {
+			t.Fatalf("%s: want error", name)
+		}
+	}
+	if _, err := LoadConfig("../../configs/risk.json"); err != nil {
+		t.Fatalf("shipped rules: %v", err)
+	}
+}
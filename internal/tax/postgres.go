@@ -0,0 +1,46 @@
+package tax
+
+import (
+	"context"
+
+	"github.com/GolangDeveloperAlmir/order-service/internal/platform/log"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store reads rules from the tax_rules table.
+type Store struct {
+	pool *pgxpool.Pool
+	log  *log.Logger
+}
+
+func NewStore(pool *pgxpool.Pool, logger *log.Logger) *Store {
+	return &Store{pool: pool, log: logger}
+}
+
+func (s *Store) Rules(ctx context.Context, country string) ([]Rule, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT country, region, tax_class, rate_bp
+		FROM tax_rules
+		WHERE country=$1`, country)
+	if err != nil {
+		s.log.Error("failed to query tax rules", log.Err(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []Rule
+	for rows.Next() {
+		var r Rule
+		if err := rows.Scan(&r.Country, &r.Region, &r.TaxClass, &r.RateBP); err != nil {
+			s.log.Error("failed to scan tax rule", log.Err(err))
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		s.log.Error("failed to query tax rules", log.Err(err))
+		return nil, err
+	}
+
+	return rules, nil
+}
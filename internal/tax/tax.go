@@ -0,0 +1,155 @@
+// Package tax computes sales tax for order lines from per-jurisdiction rules.
+package tax
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// Rule is the tax rate of a product tax class in a jurisdiction. An empty
+// Region or TaxClass matches any; the most specific rule wins.
+type Rule struct {
+	Country  string
+	Region   string
+	TaxClass string
+	// RateBP is the rate in basis points, 2000 = 20%.
+	RateBP int
+}
+
+// Rounding says where fractions of a minor unit are rounded.
+type Rounding string
+
+const (
+	// RoundPerLine rounds the tax of every line, the order tax is their sum.
+	RoundPerLine Rounding = "line"
+	// RoundPerOrder rounds the order tax once and spreads it over the lines.
+	RoundPerOrder Rounding = "order"
+)
+
+// Line is a taxable amount in minor units, after discounts.
+type Line struct {
+	TaxClass    string
+	AmountMinor int64
+}
+
+// RuleSource provides the rules of a country.
+type RuleSource interface {
+	Rules(ctx context.Context, country string) ([]Rule, error)
+}
+
+// StaticRules is a fixed rule set, for tests and local runs.
+type StaticRules []Rule
+
+func (s StaticRules) Rules(_ context.Context, country string) ([]Rule, error) {
+	var out []Rule
+	for _, r := range s {
+		if r.Country == country {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+type Calculator struct {
+	rules     RuleSource
+	inclusive bool
+	rounding  Rounding
+}
+
+// NewCalculator returns a calculator for prices that include tax when
+// inclusive is set and exclude it otherwise.
+func NewCalculator(rules RuleSource, inclusive bool, rounding Rounding) (*Calculator, error) {
+	switch rounding {
+	case RoundPerLine, RoundPerOrder:
+	default:
+		return nil, fmt.Errorf("unknown tax rounding %q", rounding)
+	}
+	return &Calculator{rules: rules, inclusive: inclusive, rounding: rounding}, nil
+}
+
+// Inclusive reports whether prices already contain tax.
+func (c *Calculator) Inclusive() bool { return c.inclusive }
+
+// Calculate returns the tax of each line in minor units. With inclusive
+// prices the tax is the part of the amount that is tax, otherwise it is added
+// on top. Halves are rounded up.
+func (c *Calculator) Calculate(ctx context.Context, country, region string, lines []Line) ([]int64, error) {
+	rules, err := c.rules.Rules(ctx, country)
+	if err != nil {
+		return nil, err
+	}
+
+	exact := make([]*big.Rat, len(lines))
+	for i, l := range lines {
+		rate := int64(rateFor(rules, region, l.TaxClass))
+		den := int64(10000)
+		if c.inclusive {
+			den += rate
+		}
+		num := new(big.Int).Mul(big.NewInt(l.AmountMinor), big.NewInt(rate))
+		exact[i] = new(big.Rat).SetFrac(num, big.NewInt(den))
+	}
+
+	taxes := make([]int64, len(lines))
+	if c.rounding == RoundPerLine {
+		for i, x := range exact {
+			taxes[i] = roundHalfUp(x)
+		}
+		return taxes, nil
+	}
+
+	// Round the sum, floor every line and hand the remaining minor units to
+	// the lines that lost the largest fractions.
+	sum := new(big.Rat)
+	frac := make([]*big.Rat, len(lines))
+	var floors int64
+	for i, x := range exact {
+		sum.Add(sum, x)
+		taxes[i] = floor(x)
+		floors += taxes[i]
+		frac[i] = new(big.Rat).Sub(x, new(big.Rat).SetInt64(taxes[i]))
+	}
+	order := make([]int, len(lines))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return frac[order[a]].Cmp(frac[order[b]]) > 0 })
+	for left, k := roundHalfUp(sum)-floors, 0; left > 0; left, k = left-1, k+1 {
+		taxes[order[k]]++
+	}
+	return taxes, nil
+}
+
+func rateFor(rules []Rule, region, class string) int {
+	best, rate := -1, 0
+	for _, r := range rules {
+		if (r.Region != "" && r.Region != region) || (r.TaxClass != "" && r.TaxClass != class) {
+			continue
+		}
+		score := 0
+		if r.Region != "" {
+			score += 2
+		}
+		if r.TaxClass != "" {
+			score++
+		}
+		if score > best {
+			best, rate = score, r.RateBP
+		}
+	}
+	return rate
+}
+
+func floor(x *big.Rat) int64 {
+	return new(big.Int).Quo(x.Num(), x.Denom()).Int64()
+}
+
+func roundHalfUp(x *big.Rat) int64 {
+	q, m := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int))
+	if m.Lsh(m, 1).Cmp(x.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q.Int64()
+}
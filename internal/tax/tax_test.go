@@ -0,0 +1,89 @@
+package tax
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+var rules = StaticRules{
+	{Country: "DE", RateBP: 1900},
+	{Country: "DE", TaxClass: "reduced", RateBP: 700},
+	{Country: "US", RateBP: 0},
+	{Country: "US", Region: "CA", RateBP: 725},
+	{Country: "US", Region: "CA", TaxClass: "groceries", RateBP: 0},
+}
+
+func TestCalculate(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name      string
+		inclusive bool
+		rounding  Rounding
+		country   string
+		region    string
+		lines     []Line
+		want      []int64
+	}{
+		{
+			name: "exclusive per line", rounding: RoundPerLine, country: "DE",
+			lines: []Line{{AmountMinor: 1000}, {TaxClass: "reduced", AmountMinor: 1000}},
+			want:  []int64{190, 70},
+		},
+		{
+			name: "inclusive per line", inclusive: true, rounding: RoundPerLine, country: "DE",
+			lines: []Line{{AmountMinor: 1190}, {TaxClass: "reduced", AmountMinor: 107}},
+			want:  []int64{190, 7},
+		},
+		{
+			name: "region overrides country, class overrides region", rounding: RoundPerLine, country: "US", region: "CA",
+			lines: []Line{{AmountMinor: 1000}, {TaxClass: "groceries", AmountMinor: 1000}},
+			want:  []int64{73, 0},
+		},
+		{
+			name: "other region", rounding: RoundPerLine, country: "US", region: "OR",
+			lines: []Line{{AmountMinor: 1000}},
+			want:  []int64{0},
+		},
+		{
+			name: "no rules for country", rounding: RoundPerLine, country: "FR",
+			lines: []Line{{AmountMinor: 1000}},
+			want:  []int64{0},
+		},
+		{
+			// 3 x 0.19 * 5 = 0.95 each: per line rounds to 1+1+1, per order 2.85 -> 3
+			name: "per line rounding", rounding: RoundPerLine, country: "DE",
+			lines: []Line{{AmountMinor: 5}, {AmountMinor: 5}, {AmountMinor: 5}},
+			want:  []int64{1, 1, 1},
+		},
+		{
+			// 2 x 0.19 * 3 = 0.57 each: per line 1+1 = 2, per order 1.14 -> 1
+			name: "per order rounding", rounding: RoundPerOrder, country: "DE",
+			lines: []Line{{AmountMinor: 3}, {AmountMinor: 3}},
+			want:  []int64{1, 0},
+		},
+		{
+			name: "per order rounding keeps every cent", rounding: RoundPerOrder, country: "DE",
+			lines: []Line{{AmountMinor: 333}, {AmountMinor: 333}, {AmountMinor: 334}},
+			want:  []int64{63, 63, 64},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewCalculator(rules, tc.inclusive, tc.rounding)
+			if err != nil {
+				t.Fatalf("calculator: %v", err)
+			}
+			got, err := c.Calculate(ctx, tc.country, tc.region, tc.lines)
+			if err != nil {
+				t.Fatalf("calculate: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v want %v", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := NewCalculator(rules, false, "nearest"); err == nil {
+		t.Fatalf("unknown rounding should be rejected")
+	}
+}
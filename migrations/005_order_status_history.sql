@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS order_status_history (
+  id           BIGSERIAL PRIMARY KEY,
+  order_id     UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  from_status  TEXT,                -- NULL for the initial "created" entry
+  to_status    TEXT NOT NULL,
+  actor        TEXT NOT NULL DEFAULT '',   -- OIDC subject, or "system"
+  reason       TEXT NOT NULL DEFAULT '',
+  request_id   TEXT NOT NULL DEFAULT '',
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order ON order_status_history(order_id, id);
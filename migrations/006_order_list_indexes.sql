@@ -0,0 +1,4 @@
+-- Keyset pagination indexes for GET /api/v1/orders sort orders.
+-- Filters on customer_id and status keep using idx_orders_customer / idx_orders_status.
+CREATE INDEX IF NOT EXISTS idx_orders_created ON orders(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_total ON orders(total_amount, id);
@@ -0,0 +1,35 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS order_items (
+  line_id      UUID PRIMARY KEY,
+  order_id     UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  line_no      INT NOT NULL,
+  sku          TEXT NOT NULL,
+  quantity     INT NOT NULL CHECK (quantity > 0),
+  price_minor  BIGINT NOT NULL CHECK (price_minor >= 0),
+  line_total   BIGINT NOT NULL,   -- quantity * price_minor
+  UNIQUE (order_id, line_no)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_sku ON order_items(sku);
+
+-- Move lines out of the legacy orders.items JSONB blob, then drop it.
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM information_schema.columns
+             WHERE table_name = 'orders' AND column_name = 'items') THEN
+    INSERT INTO order_items (line_id, order_id, line_no, sku, quantity, price_minor, line_total)
+    SELECT gen_random_uuid(), o.id, it.ord,
+           it.item->>'sku',
+           (it.item->>'quantity')::int,
+           (it.item->>'price_minor')::bigint,
+           (it.item->>'quantity')::bigint * (it.item->>'price_minor')::bigint
+    FROM orders o
+    CROSS JOIN LATERAL jsonb_array_elements(o.items) WITH ORDINALITY AS it(item, ord)
+    ON CONFLICT (order_id, line_no) DO NOTHING;
+
+    ALTER TABLE orders DROP COLUMN items;
+  END IF;
+END $$;
+
+COMMIT;
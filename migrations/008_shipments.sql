@@ -0,0 +1,23 @@
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS shipped_quantity INT NOT NULL DEFAULT 0;
+
+-- Orders shipped before partial shipments existed went out in full.
+UPDATE order_items i SET shipped_quantity = i.quantity
+FROM orders o
+WHERE o.id = i.order_id AND o.status = 'shipped' AND i.shipped_quantity = 0;
+
+CREATE TABLE IF NOT EXISTS shipments (
+  id               UUID PRIMARY KEY,
+  order_id         UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  carrier          TEXT NOT NULL,
+  tracking_number  TEXT NOT NULL,
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipments_order ON shipments(order_id);
+
+CREATE TABLE IF NOT EXISTS shipment_lines (
+  shipment_id  UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+  line_id      UUID NOT NULL REFERENCES order_items(line_id) ON DELETE CASCADE,
+  quantity     INT NOT NULL CHECK (quantity > 0),
+  PRIMARY KEY (shipment_id, line_id)
+);
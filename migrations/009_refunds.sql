@@ -0,0 +1,12 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS refunded_amount BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS refunds (
+  id            UUID PRIMARY KEY,
+  order_id      UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  amount_minor  BIGINT NOT NULL CHECK (amount_minor > 0),
+  line_ids      UUID[] NOT NULL DEFAULT '{}',
+  reason        TEXT NOT NULL DEFAULT '',
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_order ON refunds(order_id);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS order_returns (
+  id          UUID PRIMARY KEY,
+  order_id    UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  status      TEXT NOT NULL,        -- requested | approved | rejected | received | refunded
+  reason      TEXT NOT NULL DEFAULT '',
+  refund_id   UUID REFERENCES refunds(id),
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_returns_order ON order_returns(order_id);
+
+CREATE TABLE IF NOT EXISTS order_return_lines (
+  return_id  UUID NOT NULL REFERENCES order_returns(id) ON DELETE CASCADE,
+  line_id    UUID NOT NULL REFERENCES order_items(line_id) ON DELETE CASCADE,
+  quantity   INT NOT NULL CHECK (quantity > 0),
+  PRIMARY KEY (return_id, line_id)
+);
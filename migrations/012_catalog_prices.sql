@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS catalog_prices (
+  sku          TEXT NOT NULL,
+  currency     CHAR(3) NOT NULL,
+  price_minor  BIGINT NOT NULL CHECK (price_minor >= 0),
+  active       BOOLEAN NOT NULL DEFAULT true,
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (sku, currency)
+);
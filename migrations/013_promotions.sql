@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS promotions (
+  code                   TEXT PRIMARY KEY CHECK (code = upper(code)),
+  kind                   TEXT NOT NULL,        -- percentage | fixed_amount | buy_x_get_y | free_shipping
+  percent_off            INT NOT NULL DEFAULT 0,
+  amount_minor           BIGINT NOT NULL DEFAULT 0,
+  currency               CHAR(3),
+  sku                    TEXT,
+  buy_quantity           INT NOT NULL DEFAULT 0,
+  get_quantity           INT NOT NULL DEFAULT 0,
+  starts_at              TIMESTAMPTZ,
+  ends_at                TIMESTAMPTZ,
+  max_uses               INT NOT NULL DEFAULT 0,   -- 0 = unlimited
+  max_uses_per_customer  INT NOT NULL DEFAULT 0,   -- 0 = unlimited
+  stackable              BOOLEAN NOT NULL DEFAULT false,
+  uses                   INT NOT NULL DEFAULT 0,
+  active                 BOOLEAN NOT NULL DEFAULT true,
+  created_at             TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS promotion_customer_uses (
+  code         TEXT NOT NULL REFERENCES promotions(code),
+  customer_id  UUID NOT NULL,
+  uses         INT NOT NULL,
+  PRIMARY KEY (code, customer_id)
+);
+
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+  code            TEXT NOT NULL REFERENCES promotions(code),
+  order_id        UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  customer_id     UUID NOT NULL,
+  discount_minor  BIGINT NOT NULL,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (code, order_id)
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discounts JSONB NOT NULL DEFAULT '[]'::jsonb;
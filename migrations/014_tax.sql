@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS tax_rules (
+  country    CHAR(2) NOT NULL,
+  region     TEXT NOT NULL DEFAULT '',     -- '' = whole country
+  tax_class  TEXT NOT NULL DEFAULT '',     -- '' = any class
+  rate_bp    INT NOT NULL CHECK (rate_bp >= 0 AND rate_bp <= 10000),
+  PRIMARY KEY (country, region, tax_class)
+);
+
+ALTER TABLE catalog_prices ADD COLUMN IF NOT EXISTS tax_class TEXT NOT NULL DEFAULT 'standard';
+
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_class TEXT NOT NULL DEFAULT '';
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_minor BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal_amount BIGINT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_inclusive BOOLEAN NOT NULL DEFAULT false;
+
+-- Orders placed before taxes were computed carry no tax.
+UPDATE orders SET subtotal_amount = total_amount WHERE subtotal_amount IS NULL;
+ALTER TABLE orders ALTER COLUMN subtotal_amount SET NOT NULL;
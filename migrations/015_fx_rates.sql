@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS fx_rates (
+  base   CHAR(3) NOT NULL,
+  quote  CHAR(3) NOT NULL,
+  rate   NUMERIC(24,12) NOT NULL CHECK (rate > 0),   -- units of quote per unit of base
+  as_of  TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (base, quote, as_of)
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS reporting_currency CHAR(3);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS reporting_total BIGINT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fx_rate NUMERIC(24,12);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fx_rate_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_orders_reporting ON orders(reporting_currency, created_at);
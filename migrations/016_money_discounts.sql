@@ -0,0 +1,12 @@
+-- Discounts are stored with their amount as a money object:
+-- {"amount_minor": 100} becomes {"amount": {"amount_minor": 100, "currency": "USD"}}.
+UPDATE orders o
+SET discounts = (
+  SELECT COALESCE(jsonb_agg(
+           (d - 'amount_minor') || jsonb_build_object(
+             'amount', jsonb_build_object('amount_minor', COALESCE(d->'amount_minor', '0'::jsonb), 'currency', o.currency))
+           ORDER BY n), '[]'::jsonb)
+  FROM jsonb_array_elements(o.discounts) WITH ORDINALITY AS e(d, n)
+)
+WHERE jsonb_typeof(o.discounts) = 'array'
+  AND EXISTS (SELECT 1 FROM jsonb_array_elements(o.discounts) d WHERE d ? 'amount_minor');
@@ -0,0 +1,2 @@
+-- Supports the unpaid order expiry scan.
+CREATE INDEX IF NOT EXISTS idx_orders_unpaid ON orders(created_at) WHERE status = 'created';
@@ -0,0 +1,16 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS held_status TEXT;   -- status to restore when the last hold is released
+
+CREATE TABLE IF NOT EXISTS order_holds (
+  id           UUID PRIMARY KEY,
+  order_id     UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+  reason       TEXT NOT NULL,        -- fraud | address_verification | stock
+  note         TEXT NOT NULL DEFAULT '',
+  placed_by    TEXT NOT NULL DEFAULT '',
+  placed_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  released_by  TEXT,
+  released_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_holds_order ON order_holds(order_id, placed_at);
+-- one active hold per reason
+CREATE UNIQUE INDEX IF NOT EXISTS uq_order_holds_active ON order_holds(order_id, reason) WHERE released_at IS NULL;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS risk_assessments (
+  id           BIGSERIAL PRIMARY KEY,
+  order_id     UUID NOT NULL,        -- no FK: rejected orders are not stored
+  customer_id  UUID NOT NULL,
+  score        INT NOT NULL,
+  decision     TEXT NOT NULL,        -- accept | hold | reject
+  factors      JSONB NOT NULL DEFAULT '[]',
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_risk_assessments_order ON risk_assessments(order_id);
+CREATE INDEX IF NOT EXISTS idx_risk_assessments_customer ON risk_assessments(customer_id, created_at);
+
+-- velocity rules count recent orders per customer
+CREATE INDEX IF NOT EXISTS idx_orders_customer_created ON orders(customer_id, created_at);
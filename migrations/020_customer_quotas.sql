@@ -0,0 +1,9 @@
+-- Per-customer order counters. Create upserts the customer's row first, so
+-- the row lock serializes concurrent orders of one customer.
+CREATE TABLE IF NOT EXISTS customer_quotas (
+  customer_id  UUID PRIMARY KEY,
+  hour_start   TIMESTAMPTZ NOT NULL,   -- UTC clock hour hour_orders counts
+  hour_orders  INT NOT NULL DEFAULT 0,
+  day_start    TIMESTAMPTZ NOT NULL,   -- UTC day day_value counts
+  day_value    BIGINT NOT NULL DEFAULT 0  -- reporting-currency minor units
+);
@@ -0,0 +1,7 @@
+-- Events that keep failing are moved to the DLQ topic and marked dead; the
+-- relay skips them until they are requeued.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS dead_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (available_at, id) WHERE published_at IS NULL AND dead_at IS NULL;
+DROP INDEX IF EXISTS idx_outbox_available;
+CREATE INDEX IF NOT EXISTS idx_outbox_dead ON outbox (dead_at, id) WHERE dead_at IS NOT NULL;
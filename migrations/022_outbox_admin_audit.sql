@@ -0,0 +1,12 @@
+-- Audit trail of outbox admin actions (re-drive, purge).
+CREATE TABLE IF NOT EXISTS outbox_admin_audit (
+  id          BIGSERIAL PRIMARY KEY,
+  action      TEXT NOT NULL,         -- redrive | purge
+  actor       TEXT NOT NULL,
+  reason      TEXT NOT NULL DEFAULT '',
+  selector    JSONB NOT NULL,        -- state, id range and time window the action applied to
+  affected    BIGINT NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_published ON outbox (published_at, id) WHERE published_at IS NOT NULL;
@@ -0,0 +1,3 @@
+-- The relay publishes the events of an aggregate in id order and looks up
+-- earlier pending events of the aggregates it picked.
+CREATE INDEX IF NOT EXISTS idx_outbox_pending_aggregate ON outbox (aggregate_id, id) WHERE published_at IS NULL AND dead_at IS NULL;
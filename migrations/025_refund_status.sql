@@ -0,0 +1,17 @@
+-- Partial refunds no longer change the order status; the refund status is
+-- derived from refunded_amount. Restore the fulfilment status of orders
+-- (and held orders) that were moved to partially_refunded.
+WITH fulfilment AS (
+  SELECT order_id,
+         CASE WHEN bool_and(shipped_quantity >= quantity) THEN 'shipped'
+              WHEN bool_or(shipped_quantity > 0) THEN 'partially_shipped'
+              ELSE 'paid' END AS status
+  FROM order_items
+  GROUP BY order_id
+)
+UPDATE orders o
+SET status      = CASE WHEN o.status = 'partially_refunded' THEN f.status ELSE o.status END,
+    held_status = CASE WHEN o.held_status = 'partially_refunded' THEN f.status ELSE o.held_status END
+FROM fulfilment f
+WHERE f.order_id = o.id
+  AND (o.status = 'partially_refunded' OR o.held_status = 'partially_refunded');
@@ -0,0 +1,3 @@
+-- A step that failed for a temporary reason goes back to pending and is not
+-- picked again before retry_at.
+ALTER TABLE saga_steps ADD COLUMN IF NOT EXISTS retry_at TIMESTAMPTZ;
@@ -20,3 +20,33 @@ func Error(w http.ResponseWriter, status int, msg string) {
 	}
 	JSON(w, status, e{Error: msg})
 }
+
+// ProblemDetails is an RFC 9457 problem. Extensions are written as extra
+// top-level members.
+type ProblemDetails struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Extensions map[string]any
+}
+
+// Problem writes p as application/problem+json.
+func Problem(w http.ResponseWriter, p ProblemDetails) {
+	body := make(map[string]any, len(p.Extensions)+4)
+	for k, v := range p.Extensions {
+		body[k] = v
+	}
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	body["type"], body["title"], body["status"] = p.Type, p.Title, p.Status
+	if p.Detail != "" {
+		body["detail"] = p.Detail
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("failed to encode problem: %v", err)
+	}
+}